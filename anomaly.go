@@ -0,0 +1,272 @@
+package logparser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ------------- anomaly -------------- //
+
+// AnomalyOptions tunes the detection, a cost is compared with the rolling
+// median of the Window heights before it, scaled by their median absolute
+// deviation
+type AnomalyOptions struct {
+	Window int
+	// Threshold as the robust z-score from which a cost is an anomaly
+	Threshold float64
+}
+
+var DefaultAnomalyOptions = AnomalyOptions{
+	Window:    50,
+	Threshold: 5,
+}
+
+// madScale makes the MAD of normally distributed costs a standard deviation
+const madScale = 1.4826
+
+// ClassAnomaly as the Item.Class() of the detected anomalies
+const ClassAnomaly = "anomaly"
+
+var _ Item = (*Anomaly)(nil)
+
+// Anomaly as a cost spike of one height, either of the whole block or of the
+// endblocker of one module. The excess over the baseline is attributed to the
+// endblocker, handler or querier cost which exceeded its own baseline most
+type Anomaly struct {
+	height   int
+	stamp    time.Time
+	metric   string
+	module   string
+	cost     time.Duration
+	baseline time.Duration
+	score    float64
+
+	cause       string
+	causeExcess time.Duration
+}
+
+func (i *Anomaly) Data() string {
+	return fmt.Sprintf("height=%d %s module=%s cost=%s baseline=%s score=%.1f cause=%s excess=%s",
+		i.height, i.metric, i.module, i.cost, i.baseline, i.score, i.cause, i.causeExcess)
+}
+
+func (i Anomaly) Header() []string {
+	return []string{"height", "stamp", "metric", "module", "cost", "baseline", "score", "cause", "cause_excess"}
+}
+
+func (i *Anomaly) Format() []string {
+	stamp := ""
+	if !i.stamp.IsZero() {
+		stamp = i.stamp.Format(time.RFC3339)
+	}
+	return []string{
+		strconv.Itoa(i.height),
+		stamp,
+		i.metric,
+		i.module,
+		strconv.FormatInt(i.cost.Milliseconds(), 10),
+		strconv.FormatInt(i.baseline.Milliseconds(), 10),
+		strconv.FormatFloat(i.score, 'f', 1, 64),
+		i.cause,
+		strconv.FormatInt(i.causeExcess.Milliseconds(), 10),
+	}
+}
+
+func (i *Anomaly) Fields() []Field {
+	return []Field{
+		IntField("height", i.height),
+		TimeField("stamp", i.stamp),
+		StringField("metric", i.metric),
+		StringField("module", i.module),
+		DurationField("cost", i.cost),
+		DurationField("baseline", i.baseline),
+		FloatField("score", i.score),
+		StringField("cause", i.cause),
+		DurationField("cause_excess", i.causeExcess),
+	}
+}
+
+func (i *Anomaly) Stamp() time.Time {
+	return i.stamp
+}
+
+func (i Anomaly) Class() string {
+	return ClassAnomaly
+}
+
+func (i Anomaly) Level() ItemLevel {
+	return LevelWarn
+}
+
+// costSeries as the costs of the heights of one metric
+type costSeries map[int]time.Duration
+
+func median(values []time.Duration) time.Duration {
+	sorted := make([]time.Duration, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
+
+// robustScore returns the median of the window and the z-score of the cost
+// against it, false when the window is too short or flat to tell
+func robustScore(cost time.Duration, window []time.Duration, minLen int) (time.Duration, float64, bool) {
+	if len(window) < minLen {
+		return 0, 0, false
+	}
+	m := median(window)
+
+	devs := make([]time.Duration, len(window))
+	for idx, v := range window {
+		devs[idx] = v - m
+		if devs[idx] < 0 {
+			devs[idx] = -devs[idx]
+		}
+	}
+	mad := float64(median(devs)) * madScale
+	if mad == 0 {
+		return m, 0, false
+	}
+	return m, float64(cost-m) / mad, true
+}
+
+// windowOf returns the costs of the series at the heights before idx
+func windowOf(series costSeries, heights []int, idx, size int) []time.Duration {
+	from := idx - size
+	if from < 0 {
+		from = 0
+	}
+	ret := make([]time.Duration, 0, idx-from)
+	for _, h := range heights[from:idx] {
+		ret = append(ret, series[h])
+	}
+	return ret
+}
+
+// DetectAnomalies flags the heights whose block cost, or the endblocker cost
+// of one module, spiked over the rolling baseline, returns the anomalies
+// ranked by score. They are items of ClassAnomaly, so that
+// `res[ClassAnomaly] = DetectAnomalies(res, opts)` keeps them along the
+// parsed items
+func DetectAnomalies(res ParseResult, opts AnomalyOptions) []Item {
+	if opts.Window <= 0 {
+		opts.Window = DefaultAnomalyOptions.Window
+	}
+	minLen := opts.Window / 2
+	if minLen < 3 {
+		minLen = 3
+	}
+
+	blocks := costSeries{}
+	stamps := map[int]time.Time{}
+	// the components of the block cost keyed like `endblocker:bank`
+	components := map[string]costSeries{}
+	modules := map[string]costSeries{}
+	add := func(kind, name string, h int, c time.Duration) {
+		key := kind + ":" + name
+		if _, ok := components[key]; !ok {
+			components[key] = costSeries{}
+		}
+		components[key][h] += c
+	}
+
+	for _, items := range res {
+		for _, item := range items {
+			switch i := item.(type) {
+			case *TMInfoCommit:
+				if !i.costKnown {
+					continue
+				}
+				blocks[i.height] = i.cost
+				stamps[i.height] = i.stamp
+			case *TMInfoEndBlocker:
+				add("endblocker", i.module, i.height, i.cost)
+				if _, ok := modules[i.module]; !ok {
+					modules[i.module] = costSeries{}
+				}
+				modules[i.module][i.height] += i.cost
+			case *TMInfoHandler:
+				add("handler", i.txType, i.height, i.cost)
+			case *TMInfoQuerier:
+				add("querier", i.path, i.height, i.cost)
+			default:
+			}
+		}
+	}
+
+	heights := make([]int, 0, len(blocks))
+	for h := range blocks {
+		heights = append(heights, h)
+	}
+	sort.Ints(heights)
+
+	ret := []Item{}
+	for idx, h := range heights {
+		baseline, score, ok := robustScore(blocks[h], windowOf(blocks, heights, idx, opts.Window), minLen)
+		if !ok || score < opts.Threshold {
+			continue
+		}
+
+		a := &Anomaly{
+			height:   h,
+			stamp:    stamps[h],
+			metric:   "block_cost",
+			cost:     blocks[h],
+			baseline: baseline,
+			score:    score,
+		}
+		// blame the component exceeding its own baseline most
+		for key, series := range components {
+			excess := series[h] - median(windowOf(series, heights, idx, opts.Window))
+			if excess > a.causeExcess || (excess == a.causeExcess && excess > 0 && key < a.cause) {
+				a.cause, a.causeExcess = key, excess
+			}
+		}
+		ret = append(ret, a)
+	}
+
+	for module, series := range modules {
+		mHeights := make([]int, 0, len(series))
+		for h := range series {
+			mHeights = append(mHeights, h)
+		}
+		sort.Ints(mHeights)
+
+		for idx, h := range mHeights {
+			baseline, score, ok := robustScore(series[h], windowOf(series, mHeights, idx, opts.Window), minLen)
+			if !ok || score < opts.Threshold {
+				continue
+			}
+			ret = append(ret, &Anomaly{
+				height:      h,
+				stamp:       stamps[h],
+				metric:      "endblocker_cost",
+				module:      module,
+				cost:        series[h],
+				baseline:    baseline,
+				score:       score,
+				cause:       "endblocker:" + module,
+				causeExcess: series[h] - baseline,
+			})
+		}
+	}
+
+	sort.SliceStable(ret, func(a, b int) bool {
+		x, y := ret[a].(*Anomaly), ret[b].(*Anomaly)
+		if x.score != y.score {
+			return x.score > y.score
+		}
+		if x.height != y.height {
+			return x.height < y.height
+		}
+		return x.module < y.module
+	})
+	return ret
+}
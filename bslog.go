@@ -13,8 +13,12 @@ const (
 	BSLogItemCount = 6
 )
 
+func (p *Parser) RegisterBSPrefix() error {
+	return p.RegisterPrefixClassifier(BSPrefix, ParseBenchStoreItem)
+}
+
 func RegisterBSPrefix() {
-	if err := RegisterPrefixClassifier(BSPrefix, ParseBenchStoreItem); err != nil {
+	if err := defaultParser.RegisterBSPrefix(); err != nil {
 		panic(err)
 	}
 }
@@ -22,6 +26,8 @@ func RegisterBSPrefix() {
 var _ Item = (*benchStoreItem)(nil)
 
 type benchStoreItem struct {
+	itemSource
+
 	backendType   string
 	method        string
 	existingCount int
@@ -51,7 +57,7 @@ func ParseBenchStoreItem(lineNum int, lineText string) (Item, error) {
 
 	bCost, err := time.ParseDuration(strings.TrimSpace(parts[5]))
 	if err != nil {
-		return nil, fmt.Errorf("[%d] error parse cost(%s): %s", lineNum, parts[55555], err.Error())
+		return nil, fmt.Errorf("[%d] error parse cost(%s): %s", lineNum, parts[5], err.Error())
 	}
 
 	return &benchStoreItem{
@@ -75,6 +81,16 @@ func (i *benchStoreItem) Format() []string {
 	return []string{i.backendType, i.method, strconv.Itoa(i.existingCount), strconv.Itoa(i.count), strconv.FormatInt(i.cost.Milliseconds(), 10)}
 }
 
+func (i *benchStoreItem) Fields() []Field {
+	return []Field{
+		StringField("backend", i.backendType),
+		StringField("method", i.method),
+		IntField("existing", i.existingCount),
+		IntField("count", i.count),
+		DurationField("cost", i.cost),
+	}
+}
+
 func (i benchStoreItem) Stamp() time.Time {
 	return time.Time{}
 }
@@ -0,0 +1,261 @@
+// Package chart plots the parsed items, the block costs of the tendermint log
+// and the costs of the bench log, into SVG or PNG images
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+
+	"github.com/tjan147/logparser"
+)
+
+const (
+	// Width and Height as the size of the saved images
+	Width  = 20 * vg.Inch
+	Height = 10 * vg.Inch
+
+	// MaxStackedPoints as the points of a stacked layer at most, filling the
+	// area below tens of thousands of heights renders far too slow
+	MaxStackedPoints = 2000
+)
+
+// stackedCosts as the per height costs stacked from the bottom up
+var stackedCosts = []string{"endblocker_cost", "handler_cost", "querier_cost"}
+
+func intOf(item logparser.Item, name string) float64 {
+	f, _ := logparser.FieldOf(item, name)
+	return float64(f.Int())
+}
+
+func msOf(item logparser.Item, name string) float64 {
+	f, _ := logparser.FieldOf(item, name)
+	return float64(f.Duration()) / float64(time.Millisecond)
+}
+
+func newPlot(title, xLabel, yLabel string) *plot.Plot {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = xLabel
+	p.Y.Label.Text = yLabel
+	p.Legend.Top = true
+	p.Add(plotter.NewGrid())
+	return p
+}
+
+// BlockCost plots the block cost of the profiles built by
+// logparser.BuildBlockProfile against the height
+func BlockCost(profiles []logparser.Item) (*plot.Plot, error) {
+	pts := make(plotter.XYs, 0, len(profiles))
+	for _, item := range profiles {
+		// the heights whose block cost is not known are left out
+		if f, _ := logparser.FieldOf(item, "block_cost"); f.IsNull() {
+			continue
+		}
+		pts = append(pts, plotter.XY{X: intOf(item, "height"), Y: msOf(item, "block_cost")})
+	}
+
+	p := newPlot("block cost", "height", "cost (ms)")
+	line, err := plotter.NewLine(pts)
+	if err != nil {
+		return nil, fmt.Errorf("error plot block cost: %s", err.Error())
+	}
+	line.Color = plotutil.Color(0)
+	p.Add(line)
+	p.Legend.Add("block_cost", line)
+	return p, nil
+}
+
+// StackedCost plots the endblocker, handler and querier costs of the profiles
+// built by logparser.BuildBlockProfile stacked against the height
+func StackedCost(profiles []logparser.Item) (*plot.Plot, error) {
+	p := newPlot("stacked cost", "height", "cost (ms)")
+	// the areas are filled down to the bottom of the axis
+	p.Y.Min = 0
+
+	// each layer sums the layers below, the top one is drawn first so that the
+	// lower ones cover it
+	layers := make([]*plotter.Line, len(stackedCosts))
+	sums := make([]float64, len(profiles))
+	for idx, name := range stackedCosts {
+		pts := make(plotter.XYs, 0, len(profiles))
+		for row, item := range profiles {
+			sums[row] += msOf(item, name)
+			pts = append(pts, plotter.XY{X: intOf(item, "height"), Y: sums[row]})
+		}
+
+		line, err := plotter.NewLine(downsample(pts, MaxStackedPoints))
+		if err != nil {
+			return nil, fmt.Errorf("error plot %s: %s", name, err.Error())
+		}
+		line.Color = plotutil.Color(idx)
+		line.FillColor = fade(plotutil.Color(idx))
+		layers[idx] = line
+	}
+	for idx := len(layers) - 1; idx >= 0; idx-- {
+		p.Add(layers[idx])
+	}
+	for idx, name := range stackedCosts {
+		p.Legend.Add(name, layers[idx])
+	}
+	return p, nil
+}
+
+// downsample averages the runs of consecutive points down to max points
+func downsample(pts plotter.XYs, max int) plotter.XYs {
+	if len(pts) <= max {
+		return pts
+	}
+
+	ret := make(plotter.XYs, 0, max)
+	for idx := 0; idx < max; idx++ {
+		from, to := idx*len(pts)/max, (idx+1)*len(pts)/max
+		var sum plotter.XY
+		for _, pt := range pts[from:to] {
+			sum.X += pt.X
+			sum.Y += pt.Y
+		}
+		n := float64(to - from)
+		ret = append(ret, plotter.XY{X: sum.X / n, Y: sum.Y / n})
+	}
+	return ret
+}
+
+// fade lightens the color halfway to white, opaque so that the lower layers
+// cover the upper ones
+func fade(c color.Color) color.Color {
+	r, g, b, _ := c.RGBA()
+	return color.RGBA{R: uint8((r>>8 + 0xff) / 2), G: uint8((g>>8 + 0xff) / 2), B: uint8((b>>8 + 0xff) / 2), A: 0xff}
+}
+
+// ModuleCost plots the endblocker cost summed over all the heights as a bar
+// per module
+func ModuleCost(res logparser.ParseResult) (*plot.Plot, error) {
+	costs := map[string]float64{}
+	for _, items := range res {
+		for _, item := range items {
+			module, ok := logparser.FieldOf(item, "module")
+			if !ok {
+				continue
+			}
+			if _, ok := logparser.FieldOf(item, "endblocker_cost"); ok {
+				costs[module.String()] += msOf(item, "endblocker_cost")
+			}
+		}
+	}
+
+	modules := make([]string, 0, len(costs))
+	for module := range costs {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	values := make(plotter.Values, 0, len(modules))
+	for _, module := range modules {
+		values = append(values, costs[module])
+	}
+
+	p := newPlot("endblocker cost by module", "module", "total cost (ms)")
+	if len(values) == 0 {
+		return p, nil
+	}
+	bars, err := plotter.NewBarChart(values, vg.Points(40))
+	if err != nil {
+		return nil, fmt.Errorf("error plot module cost: %s", err.Error())
+	}
+	bars.Color = plotutil.Color(0)
+	bars.LineStyle.Width = 0
+	p.Add(bars)
+	p.NominalX(modules...)
+	return p, nil
+}
+
+// BenchClasses returns the classes of the bench items in res
+func BenchClasses(res logparser.ParseResult) []string {
+	ret := []string{}
+	for class, items := range res {
+		if len(items) == 0 {
+			continue
+		}
+		_, hasBackend := logparser.FieldOf(items[0], "backend")
+		_, hasExisting := logparser.FieldOf(items[0], "existing")
+		if hasBackend && hasExisting {
+			ret = append(ret, class)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// BenchCost plots the cost of the bench items of one class against the
+// existing count, a line per backend
+func BenchCost(class string, items []logparser.Item) (*plot.Plot, error) {
+	backends := map[string]plotter.XYs{}
+	for _, item := range items {
+		f, ok := logparser.FieldOf(item, "backend")
+		if !ok {
+			continue
+		}
+		backend := f.String()
+		backends[backend] = append(backends[backend], plotter.XY{X: intOf(item, "existing"), Y: msOf(item, "cost")})
+	}
+
+	names := make([]string, 0, len(backends))
+	for backend := range backends {
+		names = append(names, backend)
+	}
+	sort.Strings(names)
+
+	p := newPlot(class+" cost", "existing", "cost (ms)")
+	for idx, backend := range names {
+		pts := backends[backend]
+		sort.SliceStable(pts, func(a, b int) bool { return pts[a].X < pts[b].X })
+
+		line, err := plotter.NewLine(pts)
+		if err != nil {
+			return nil, fmt.Errorf("error plot %s of %s: %s", class, backend, err.Error())
+		}
+		line.Color = plotutil.Color(idx)
+		p.Add(line)
+		p.Legend.Add(backend, line)
+	}
+	return p, nil
+}
+
+// Encode renders the plot in the format, svg or png
+func Encode(p *plot.Plot, format string) ([]byte, error) {
+	switch format {
+	case "svg", "png":
+	default:
+		return nil, fmt.Errorf("unknown image format %s", format)
+	}
+
+	w, err := p.WriterTo(Width, Height, format)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Save renders the plot in the format of the path extension, svg or png
+func Save(p *plot.Plot, path string) error {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".svg", ".png":
+	default:
+		return fmt.Errorf("unknown image format %s", ext)
+	}
+	return p.Save(Width, Height, path)
+}
@@ -0,0 +1,176 @@
+package logparser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// TMSnapshot as the serializable form of a TMState, the log date given is
+// left to the caller
+type TMSnapshot struct {
+	Height      int       `json:"height"`
+	HeightStamp time.Time `json:"height_stamp"`
+	Committed   bool      `json:"committed"`
+
+	// the day the cometbft stamps of the log source fell on last
+	LogSource string    `json:"log_source,omitempty"`
+	LogDay    time.Time `json:"log_day"`
+	LogLast   time.Time `json:"log_last"`
+}
+
+func (s *TMState) Snapshot() TMSnapshot {
+	return TMSnapshot{
+		Height:      s.height,
+		HeightStamp: s.heightStamp,
+		Committed:   s.committed,
+		LogSource:   s.date.source,
+		LogDay:      s.date.day,
+		LogLast:     s.date.last,
+	}
+}
+
+func (s *TMState) Restore(snap TMSnapshot) {
+	s.height = snap.Height
+	s.heightStamp = snap.HeightStamp
+	s.committed = snap.Committed
+	s.date.source = snap.LogSource
+	s.date.day = snap.LogDay
+	s.date.last = snap.LogLast
+}
+
+// Checkpoint records how far an append-only log was parsed together with the
+// parser state at that point, so the next run resumes right after it
+type Checkpoint struct {
+	Path   string     `json:"path"`
+	Offset int64      `json:"offset"`
+	Lines  int        `json:"lines"`
+	Inode  uint64     `json:"inode"`
+	TM     TMSnapshot `json:"tm"`
+}
+
+// LoadCheckpoint reads the checkpoint saved at path, it returns nil without
+// error when there is none yet
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("error parse checkpoint %s: %s", path, err.Error())
+	}
+	return cp, nil
+}
+
+// Save writes the checkpoint to path, replacing the previous one at once
+func (cp *Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// lastLineEnd returns the offset right after the last newline of the file, a
+// partial last line is left for the next run to complete
+func lastLineEnd(file *os.File, size int64) (int64, error) {
+	buf := make([]byte, 64*1024)
+	end := size
+	for end > 0 {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		n, err := file.ReadAt(buf[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		for idx := n - 1; idx >= 0; idx-- {
+			if buf[idx] == '\n' {
+				return start + int64(idx) + 1, nil
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// ParseIncremental parses the lines appended to the log at path since the
+// checkpoint cp, a nil cp parses from the start. A log replaced or truncated
+// since the checkpoint is parsed from its start again, still carrying the
+// parser state over. Returns the checkpoint to resume from next time
+func (p *Parser) ParseIncremental(ctx context.Context, path string, cp *Checkpoint, emit EmitFunc) (*Checkpoint, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	magic := make([]byte, 4)
+	n, err := file.ReadAt(magic, 0)
+	if err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	if isCompressed(magic[:n]) {
+		return nil, 0, fmt.Errorf("%s is compressed, only a plain log can be parsed incrementally", path)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	inode := fileInode(info)
+
+	offset, lineBase := int64(0), 0
+	if cp != nil {
+		p.tm.Restore(cp.TM)
+		if cp.Inode == inode && cp.Offset <= info.Size() {
+			offset, lineBase = cp.Offset, cp.Lines
+		}
+	}
+
+	end, err := lastLineEnd(file, info.Size())
+	if err != nil {
+		return nil, 0, err
+	}
+	if end < offset {
+		end = offset
+	}
+
+	p.report = &ParseReport{}
+	p.source = path
+	defer func() {
+		p.source = ""
+	}()
+
+	cnt, err := p.scan(ctx, io.NewSectionReader(file, offset, end-offset), lineBase, emit)
+	if err != nil {
+		return nil, cnt, err
+	}
+
+	return &Checkpoint{
+		Path:   path,
+		Offset: end,
+		Lines:  lineBase + cnt,
+		Inode:  inode,
+		TM:     p.tm.Snapshot(),
+	}, cnt, nil
+}
+
+func ParseIncremental(ctx context.Context, path string, cp *Checkpoint, emit EmitFunc) (*Checkpoint, int, error) {
+	return defaultParser.ParseIncremental(ctx, path, cp, emit)
+}
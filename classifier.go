@@ -0,0 +1,241 @@
+package logparser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CaptureParseFunc as the ParseFunc of the regex classifiers, it receives the
+// named capture groups of the match as well
+type CaptureParseFunc = func(int, string, map[string]string) (Item, error)
+
+// MatchFunc reports whether a line belongs to a matcher classifier
+type MatchFunc = func(string) bool
+
+// stateParseFunc as the parser reaching the format state of its Parser
+type stateParseFunc = func(*Parser, int, string, map[string]string) (Item, error)
+
+// ClassifierInfo.Kind values
+const (
+	ClassifierPrefix  = "prefix"
+	ClassifierRegex   = "regex"
+	ClassifierMatcher = "matcher"
+)
+
+// ClassifierInfo describes a registered classifier, Pattern holds the prefix,
+// the expression or the matcher name according to the Kind
+type ClassifierInfo struct {
+	Kind     string
+	Pattern  string
+	Priority int
+}
+
+func (c ClassifierInfo) String() string {
+	return fmt.Sprintf("%s %q (priority %d)", c.Kind, c.Pattern, c.Priority)
+}
+
+type classifier struct {
+	info  ClassifierInfo
+	order int
+	match func(string) (map[string]string, bool)
+	parse stateParseFunc
+}
+
+// specificity ranks the classifiers of the same priority, a longer prefix is
+// more specific while regex and matcher classifiers rank after all prefixes
+func (c *classifier) specificity() int {
+	if c.info.Kind == ClassifierPrefix {
+		return len(c.info.Pattern)
+	}
+	return 0
+}
+
+// before reports whether a is tried before b: higher priority first, then the
+// more specific, then the earlier registration
+func (a *classifier) before(b *classifier) bool {
+	if a.info.Priority != b.info.Priority {
+		return a.info.Priority > b.info.Priority
+	}
+	if a.specificity() != b.specificity() {
+		return a.specificity() > b.specificity()
+	}
+	return a.order < b.order
+}
+
+// addClassifier keeps the table sorted in the order lines are matched
+func (p *Parser) addClassifier(c *classifier) error {
+	for _, exist := range p.classifiers {
+		if exist.info.Kind == c.info.Kind && exist.info.Pattern == c.info.Pattern {
+			return fmt.Errorf("%s %s already taken", c.info.Kind, c.info.Pattern)
+		}
+	}
+
+	c.order = p.registered
+	p.registered++
+
+	idx := sort.Search(len(p.classifiers), func(i int) bool {
+		return c.before(p.classifiers[i])
+	})
+	p.classifiers = append(p.classifiers, nil)
+	copy(p.classifiers[idx+1:], p.classifiers[idx:])
+	p.classifiers[idx] = c
+	return nil
+}
+
+func (p *Parser) classify(lineText string) (*classifier, map[string]string) {
+	for _, c := range p.classifiers {
+		if groups, ok := c.match(lineText); ok {
+			return c, groups
+		}
+	}
+	return nil, nil
+}
+
+// Classify returns the classifier claiming the line, false if none does
+func (p *Parser) Classify(lineText string) (ClassifierInfo, bool) {
+	c, _ := p.classify(lineText)
+	if c == nil {
+		return ClassifierInfo{}, false
+	}
+	return c.info, true
+}
+
+// Classifiers lists the registered classifiers in the order they are tried
+func (p *Parser) Classifiers() []ClassifierInfo {
+	ret := make([]ClassifierInfo, 0, len(p.classifiers))
+	for _, c := range p.classifiers {
+		ret = append(ret, c.info)
+	}
+	return ret
+}
+
+func Classifiers() []ClassifierInfo {
+	return defaultParser.Classifiers()
+}
+
+// ------------- prefix --------------- //
+
+func (p *Parser) registerPrefix(prefix string, priority int, parser stateParseFunc) error {
+	return p.addClassifier(&classifier{
+		info: ClassifierInfo{
+			Kind:     ClassifierPrefix,
+			Pattern:  prefix,
+			Priority: priority,
+		},
+		match: func(lineText string) (map[string]string, bool) {
+			return nil, strings.HasPrefix(lineText, prefix)
+		},
+		parse: parser,
+	})
+}
+
+// RegisterPrefixClassifierWithPriority registers a prefix classifier which is
+// tried before every classifier of a lower priority, among the same priority
+// the longest matching prefix wins
+func (p *Parser) RegisterPrefixClassifierWithPriority(prefix string, priority int, parser ParseFunc) error {
+	return p.registerPrefix(prefix, priority, func(_ *Parser, lineNum int, lineText string, _ map[string]string) (Item, error) {
+		return parser(lineNum, lineText)
+	})
+}
+
+func (p *Parser) RegisterPrefixClassifier(prefix string, parser ParseFunc) error {
+	return p.RegisterPrefixClassifierWithPriority(prefix, 0, parser)
+}
+
+func RegisterPrefixClassifierWithPriority(prefix string, priority int, parser ParseFunc) error {
+	return defaultParser.RegisterPrefixClassifierWithPriority(prefix, priority, parser)
+}
+
+func RegisterPrefixClassifier(prefix string, parser ParseFunc) error {
+	return defaultParser.RegisterPrefixClassifier(prefix, parser)
+}
+
+// ------------- regex --------------- //
+
+func (p *Parser) registerRegex(expr string, priority int, parser stateParseFunc) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("error compile classifier regex(%s): %s", expr, err.Error())
+	}
+	names := re.SubexpNames()
+
+	return p.addClassifier(&classifier{
+		info: ClassifierInfo{
+			Kind:     ClassifierRegex,
+			Pattern:  expr,
+			Priority: priority,
+		},
+		match: func(lineText string) (map[string]string, bool) {
+			found := re.FindStringSubmatch(lineText)
+			if found == nil {
+				return nil, false
+			}
+			groups := map[string]string{}
+			for idx, name := range names {
+				if len(name) > 0 {
+					groups[name] = found[idx]
+				}
+			}
+			return groups, true
+		},
+		parse: parser,
+	})
+}
+
+// RegisterRegexClassifierWithPriority registers a classifier claiming the
+// lines matched by expr, the named capture groups are handed to the parser
+func (p *Parser) RegisterRegexClassifierWithPriority(expr string, priority int, parser CaptureParseFunc) error {
+	return p.registerRegex(expr, priority, func(_ *Parser, lineNum int, lineText string, groups map[string]string) (Item, error) {
+		return parser(lineNum, lineText, groups)
+	})
+}
+
+func (p *Parser) RegisterRegexClassifier(expr string, parser CaptureParseFunc) error {
+	return p.RegisterRegexClassifierWithPriority(expr, 0, parser)
+}
+
+func RegisterRegexClassifierWithPriority(expr string, priority int, parser CaptureParseFunc) error {
+	return defaultParser.RegisterRegexClassifierWithPriority(expr, priority, parser)
+}
+
+func RegisterRegexClassifier(expr string, parser CaptureParseFunc) error {
+	return defaultParser.RegisterRegexClassifier(expr, parser)
+}
+
+// ------------- matcher --------------- //
+
+func (p *Parser) registerMatcher(name string, priority int, match MatchFunc, parser stateParseFunc) error {
+	return p.addClassifier(&classifier{
+		info: ClassifierInfo{
+			Kind:     ClassifierMatcher,
+			Pattern:  name,
+			Priority: priority,
+		},
+		match: func(lineText string) (map[string]string, bool) {
+			return nil, match(lineText)
+		},
+		parse: parser,
+	})
+}
+
+// RegisterMatcherClassifierWithPriority registers a classifier claiming the
+// lines accepted by match, name identifies it in the classifier listing
+func (p *Parser) RegisterMatcherClassifierWithPriority(name string, priority int, match MatchFunc, parser ParseFunc) error {
+	return p.registerMatcher(name, priority, match, func(_ *Parser, lineNum int, lineText string, _ map[string]string) (Item, error) {
+		return parser(lineNum, lineText)
+	})
+}
+
+func (p *Parser) RegisterMatcherClassifier(name string, match MatchFunc, parser ParseFunc) error {
+	return p.RegisterMatcherClassifierWithPriority(name, 0, match, parser)
+}
+
+func RegisterMatcherClassifierWithPriority(name string, priority int, match MatchFunc, parser ParseFunc) error {
+	return defaultParser.RegisterMatcherClassifierWithPriority(name, priority, match, parser)
+}
+
+func RegisterMatcherClassifier(name string, match MatchFunc, parser ParseFunc) error {
+	return defaultParser.RegisterMatcherClassifier(name, match, parser)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tjan147/logparser"
+)
+
+// runAnomaly exports the cost spikes of the inputs and lists them ranked by
+// score
+func runAnomaly(ctx context.Context, parser *logparser.Parser, patterns []string) {
+	res, outName := parseAll(ctx, parser, patterns)
+
+	opts := logparser.AnomalyOptions{
+		Window:    *anomalyWindow,
+		Threshold: *anomalyScore,
+	}
+	res[logparser.ClassAnomaly] = logparser.DetectAnomalies(res, opts)
+	anomalies := res[logparser.ClassAnomaly]
+
+	exp := newExporter(*output, outName, analysisSuffix(logparser.ClassAnomaly), *format, false, false)
+	for _, a := range anomalies {
+		if err := exp.emit(a); err != nil {
+			panic(err)
+		}
+	}
+	exp.meta["inputs"] = strings.Join(patterns, " ")
+	exp.meta["date"] = *filterDate
+	exp.meta["anomalies"] = strconv.Itoa(len(anomalies))
+	exp.meta["parsed_at"] = time.Now().Format(time.RFC3339)
+	if err := exp.close(); err != nil {
+		panic(err)
+	}
+	recordTargetName(strings.Join(exp.outNames, "\n"))
+
+	fmt.Printf("%d anomalies found\n", len(anomalies))
+	for idx, a := range anomalies {
+		fmt.Printf("%d: %s\n", idx+1, a.Data())
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tjan147/logparser"
+)
+
+// runDiff compares the cost statistics of a base run and a head run, each
+// given as the logs or as a jsonl export, exits with 1 when any of them
+// regressed
+func runDiff(ctx context.Context, parser *logparser.Parser, patterns []string) {
+	if len(patterns) != 2 {
+		panic("diff takes the base and the head inputs")
+	}
+
+	base := loadStats(ctx, parser, patterns[0])
+	head := loadStats(ctx, newParser(), patterns[1])
+
+	opts := logparser.DiffOptions{
+		Quantile:  *diffQuantile,
+		Threshold: *diffThreshold,
+		Alpha:     *diffAlpha,
+	}
+	diffs, err := logparser.CompareStats(base, head, opts)
+	if err != nil {
+		panic(err)
+	}
+
+	outName := outNameOr(filepath.Base(patterns[0]) + "-" + filepath.Base(patterns[1]))
+	exp := newExporter(*output, outName, analysisSuffix(logparser.ClassStatDiff), *format, false, false)
+	regressed := 0
+	for _, d := range diffs {
+		if err := exp.emit(d); err != nil {
+			panic(err)
+		}
+		if d.Regressed() {
+			regressed++
+		}
+	}
+	exp.meta["base"] = patterns[0]
+	exp.meta["head"] = patterns[1]
+	exp.meta["parsed_at"] = time.Now().Format(time.RFC3339)
+	if err := exp.close(); err != nil {
+		panic(err)
+	}
+	recordTargetName(strings.Join(exp.outNames, "\n"))
+
+	printDiffs(diffs)
+	if regressed > 0 {
+		fmt.Printf("%d regressed over %.0f%% of %s (alpha %.2f)\n", regressed, opts.Threshold*100, opts.Quantile, opts.Alpha)
+		os.Exit(1)
+	}
+	fmt.Println("no regression")
+}
+
+// loadStats parses the logs of the pattern, or reads the jsonl exports back
+func loadStats(ctx context.Context, parser *logparser.Parser, pattern string) *logparser.Stats {
+	stats := logparser.NewStats()
+	if !isJSONL(pattern) {
+		parseInto(ctx, parser, []string{pattern}, stats.Emit)
+		return stats
+	}
+
+	paths, err := logparser.ExpandInputs([]string{pattern})
+	if err != nil {
+		panic(err)
+	}
+	for _, p := range paths {
+		fmt.Printf("start reading %s ...\n", p)
+		r, err := logparser.OpenLog(p)
+		if err != nil {
+			panic(err)
+		}
+		cnt, err := logparser.ReadJSONL(r, stats.Emit)
+		r.Close()
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%d records successfully read\n", cnt)
+	}
+	return stats
+}
+
+// isJSONL tells the jsonl exports, compressed or not, from the logs
+func isJSONL(pattern string) bool {
+	base := filepath.Base(pattern)
+	return strings.HasSuffix(base, ".jsonl") || strings.Contains(base, ".jsonl.")
+}
+
+// printDiffs lists the regressed statistics first
+func printDiffs(diffs []*logparser.StatDiff) {
+	if len(diffs) == 0 {
+		fmt.Println("no costs found")
+		return
+	}
+
+	fmt.Println(strings.Join(diffs[0].Header(), "\t"))
+	for _, regressed := range []bool{true, false} {
+		for _, d := range diffs {
+			if d.Regressed() == regressed {
+				fmt.Println(strings.Join(d.Format(), "\t"))
+			}
+		}
+	}
+}
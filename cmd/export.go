@@ -0,0 +1,286 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/tjan147/logparser"
+	"github.com/tjan147/logparser/parquet"
+)
+
+// output formats
+const (
+	formatCSV     = "csv"
+	formatJSONL   = "jsonl"
+	formatSQLite  = "sqlite"
+	formatParquet = "parquet"
+)
+
+// itemSink as the common part of the sinks of every output format
+type itemSink interface {
+	Emit(logparser.Item) error
+	Flush() error
+	Close() error
+}
+
+// csvSink lists the files of all the classes written
+type csvSink struct {
+	*logparser.CSVSink
+}
+
+func (s csvSink) paths() []string {
+	classes := s.Classes()
+	sort.Strings(classes)
+
+	ret := make([]string, 0, len(classes))
+	for _, class := range classes {
+		ret = append(ret, s.PathOf(class))
+	}
+	return ret
+}
+
+type jsonlSink struct {
+	*logparser.JSONLSink
+	path string
+}
+
+func (s jsonlSink) paths() []string {
+	return []string{s.path}
+}
+
+// parquetSink lists the files of all the classes written
+type parquetSink struct {
+	*parquet.Sink
+}
+
+func (s parquetSink) paths() []string {
+	classes := s.Classes()
+	sort.Strings(classes)
+
+	ret := make([]string, 0, len(classes))
+	for _, class := range classes {
+		ret = append(ret, s.PathOf(class))
+	}
+	return ret
+}
+
+// sqliteSink gathers the whole result, then writes it into the db on close
+type sqliteSink struct {
+	path string
+	res  logparser.ParseResult
+	meta map[string]string
+}
+
+func (s *sqliteSink) Emit(item logparser.Item) error {
+	s.res[item.Class()] = append(s.res[item.Class()], item)
+	return nil
+}
+
+func (s *sqliteSink) Flush() error {
+	return nil
+}
+
+func (s *sqliteSink) Close() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	db, err := sql.Open("sqlite3", s.path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return logparser.SaveAsSQLite(db, s.res, s.meta)
+}
+
+func (s *sqliteSink) paths() []string {
+	return []string{s.path}
+}
+
+// analysisSuffix keeps the single file formats from overwriting the export of
+// the parsed items with the rows of the class computed from them
+func analysisSuffix(class string) string {
+	switch *format {
+	case formatJSONL, formatSQLite:
+		return datePart() + "." + class
+	default:
+	}
+	return datePart()
+}
+
+// exporter routes the parsed items into the sinks of the output format,
+// either one set of files for the whole run or one set per source file
+type exporter struct {
+	dir      string
+	name     string
+	suffix   string
+	format   string
+	split    bool
+	withSrc  bool
+	appendTo bool
+	live     bool
+	sinks    map[string]itemSink
+	outNames []string
+	// meta as the run metadata of the formats able to hold it
+	meta map[string]string
+	// parquetOpts as the options of the parquet format
+	parquetOpts parquet.Options
+}
+
+func newExporter(dir, name, suffix, format string, split, withSrc bool) *exporter {
+	return &exporter{
+		dir:     dir,
+		name:    name,
+		suffix:  suffix,
+		format:  format,
+		split:   split,
+		withSrc: withSrc,
+		sinks:   map[string]itemSink{},
+		meta:    map[string]string{},
+		parquetOpts: parquet.Options{
+			RowGroupSize: *parquetRowGroup,
+			Compression:  *parquetCompression,
+		},
+	}
+}
+
+func (e *exporter) newSink(outName string) (itemSink, error) {
+	switch e.format {
+	case formatCSV:
+		sink := logparser.NewCSVSink(e.dir, outName)
+		if e.withSrc && !e.split {
+			sink.WithSource()
+		}
+		if e.appendTo {
+			sink.WithAppend()
+		}
+		return csvSink{sink}, nil
+	case formatJSONL:
+		outPath := path.Join(e.dir, outName+".jsonl")
+		sink, err := logparser.CreateJSONLSink(outPath, e.appendTo)
+		if err != nil {
+			return nil, err
+		}
+		return jsonlSink{sink, outPath}, nil
+	case formatParquet:
+		if e.appendTo {
+			return nil, fmt.Errorf("the %s format can not be appended to", e.format)
+		}
+		return parquetSink{parquet.NewSink(e.dir, outName, e.parquetOpts)}, nil
+	case formatSQLite:
+		if e.appendTo {
+			return nil, fmt.Errorf("the %s format can not be appended to", e.format)
+		}
+		return &sqliteSink{
+			path: path.Join(e.dir, outName+".sqlite"),
+			res:  logparser.ParseResult{},
+			meta: e.meta,
+		}, nil
+	default:
+	}
+	return nil, fmt.Errorf("unknown output format %s", e.format)
+}
+
+func (e *exporter) sinkOf(item logparser.Item) (itemSink, error) {
+	name := e.name
+	if e.split {
+		if src, ok := item.(logparser.Sourced); ok && len(src.Source()) > 0 {
+			name = filepath.Base(src.Source())
+		}
+	}
+
+	sink, ok := e.sinks[name]
+	if !ok {
+		var err error
+		if sink, err = e.newSink(name + e.suffix); err != nil {
+			return nil, err
+		}
+		e.sinks[name] = sink
+		e.outNames = append(e.outNames, name+e.suffix)
+	}
+	return sink, nil
+}
+
+// appending makes the sinks append to the existing files
+func (e *exporter) appending() *exporter {
+	e.appendTo = true
+	return e
+}
+
+// following makes the sinks append to the existing files and flush every
+// item, so the exports grow along with the followed log
+func (e *exporter) following() *exporter {
+	e.appendTo = true
+	e.live = true
+	return e
+}
+
+func (e *exporter) emit(item logparser.Item) error {
+	sink, err := e.sinkOf(item)
+	if err != nil {
+		return err
+	}
+	if err := sink.Emit(item); err != nil {
+		return err
+	}
+	if e.live {
+		return sink.Flush()
+	}
+	return nil
+}
+
+// close flushes and closes every sink, returns the first error so that the
+// caller does not take a failed export for done
+func (e *exporter) close() error {
+	return e.finish(true)
+}
+
+// abort closes every sink of an export cut short, the files written so far are
+// not reported as exported and the sqlite db is left as it was
+func (e *exporter) abort() error {
+	return e.finish(false)
+}
+
+func (e *exporter) finish(complete bool) error {
+	var ret error
+	for _, sink := range e.sinks {
+		if _, ok := sink.(*sqliteSink); ok && !complete {
+			// the db is only written once the export is complete
+			continue
+		}
+		if err := sink.Close(); err != nil {
+			fmt.Printf("error closing exported data: %s\n", err.Error())
+			if ret == nil {
+				ret = err
+			}
+			continue
+		}
+
+		var paths []string
+		switch s := sink.(type) {
+		case csvSink:
+			paths = s.paths()
+		case jsonlSink:
+			paths = s.paths()
+		case *sqliteSink:
+			paths = s.paths()
+		case parquetSink:
+			paths = s.paths()
+		default:
+		}
+		for _, p := range paths {
+			if complete {
+				fmt.Printf("data successfully exported to %s\n", p)
+			} else {
+				fmt.Printf("incomplete data left in %s\n", p)
+			}
+		}
+	}
+	return ret
+}
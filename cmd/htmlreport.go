@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/tjan147/logparser"
+	"github.com/tjan147/logparser/htmlreport"
+)
+
+// runReport writes the self-contained html report of the inputs
+func runReport(ctx context.Context, parser *logparser.Parser, patterns []string) {
+	res, outName := parseAll(ctx, parser, patterns)
+
+	out := path.Join(*output, outName+datePart()+".report.html")
+	opts := htmlreport.Options{
+		Title:      outName + datePart(),
+		Inputs:     patterns,
+		TopHeights: *reportTop,
+		MaxRows:    *reportRows,
+	}
+	if err := htmlreport.Save(out, res, parser.Report(), opts); err != nil {
+		panic(err)
+	}
+	recordTargetName(outName + datePart())
+	fmt.Printf("report successfully written to %s\n", out)
+}
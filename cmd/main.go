@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/tjan147/logparser"
+	"github.com/tjan147/logparser/htmlreport"
+	"github.com/tjan147/logparser/parquet"
 )
 
 const (
@@ -20,10 +25,41 @@ const (
 )
 
 var (
-	input  = flag.String("i", defaultInput, "the path of the input log file")
-	output = flag.String("o", defaultOutput, "the path of the output folder")
+	input   = flag.String("i", defaultInput, "the path or glob of the input log files, - for stdin, more inputs may follow the flags")
+	rotated = flag.Bool("rotated", false, "parse each input together with its rotated files like input.1, input.2.gz")
+	output  = flag.String("o", defaultOutput, "the path of the output folder")
+	format  = flag.String("format", formatCSV, "the output format, csv for a file per class, jsonl for a single JSON Lines file, sqlite for a db with a table per class or parquet for a parquet file per class")
+
+	plotFormat    = flag.String("plot-format", "png", "the image format of the plot subcommand, png or svg")
+	reportTop     = flag.Int("report-top", htmlreport.DefaultTopHeights, "the slowest heights listed by the report subcommand")
+	reportRows    = flag.Int("report-rows", htmlreport.DefaultMaxRows, "the rows of every class table shown by the report subcommand")
+	diffQuantile  = flag.String("quantile", logparser.DefaultDiffOptions.Quantile, "the quantile compared by the diff subcommand: p50, p90, p99 or p999")
+	diffThreshold = flag.Float64("threshold", logparser.DefaultDiffOptions.Threshold, "the relative growth of the quantile the diff subcommand counts as a regression")
+	diffAlpha     = flag.Float64("alpha", logparser.DefaultDiffOptions.Alpha, "the significance level of the t-test of the diff subcommand")
+	anomalyWindow = flag.Int("window", logparser.DefaultAnomalyOptions.Window, "the heights before a cost the anomaly subcommand compares it with")
+	anomalyScore  = flag.Float64("zscore", logparser.DefaultAnomalyOptions.Threshold, "the robust z-score from which the anomaly subcommand flags a cost")
+
+	parquetCompression = flag.String("parquet-compression", "snappy", "the compression of the parquet files: none, snappy, gzip or zstd")
+	parquetRowGroup    = flag.Int64("parquet-row-group", parquet.DefaultRowGroupSize, "the bytes of a parquet row group")
+	name               = flag.String("name", "", "the name prefix of the merged output files, defaults to the input name")
+	split              = flag.Bool("split", false, "export the items of each input file separately instead of merging them")
+	workers            = flag.Int("workers", 1, "parse each file in chunks on this many workers")
+
+	checkpoint = flag.String("checkpoint", "", "resume parsing the input after the checkpoint saved in this file, appending to the exports")
+
+	follow    = flag.Bool("f", false, "follow the input log like tail -F, appending the new items to the exports until interrupted")
+	fromStart = flag.Bool("from-start", false, "parse the existing content of the followed log first")
+	poll      = flag.Duration("poll", logparser.DefaultFollowPoll, "the interval between checks of the followed log")
 
 	filterDate = flag.String("date", "", "the date of selected log items")
+	logDate    = flag.String("log-date", "", "the date of the logs in the cometbft plain format, which omits it, defaults to the -date or the date in the file name")
+
+	lenient       = flag.Bool("lenient", false, "collect malformed lines into a report instead of aborting")
+	maxErrors     = flag.Int("max-errors", -1, "abort the lenient parsing once more lines failed, -1 for no limit")
+	keepMalformed = flag.Bool("malformed", false, "export the malformed lines as a class in lenient mode")
+
+	listClassifiers = flag.Bool("classifiers", false, "list the registered classifiers in matching order and exit")
+	classifyLine    = flag.String("classify", "", "show which classifier claims the given line and exit")
 )
 
 func recordTargetName(target string) {
@@ -40,9 +76,162 @@ func recordTargetName(target string) {
 	}
 }
 
+// subcommands run on the whole parse result gathered in memory instead of
+// streaming the items into the exports, invoked like `cmd profile -i x.log`
+var subcommands = map[string]func(ctx context.Context, parser *logparser.Parser, patterns []string){
+	"profile":  runProfile,
+	"stats":    runStats,
+	"plot":     runPlot,
+	"report":   runReport,
+	"diff":     runDiff,
+	"anomaly":  runAnomaly,
+	"timeline": runTimeline,
+}
+
 func main() {
+	// the subcommand goes before the flags
+	args := os.Args[1:]
+	var sub func(context.Context, *logparser.Parser, []string)
+	if len(args) > 0 {
+		if run, ok := subcommands[args[0]]; ok {
+			sub = run
+			args = args[1:]
+		}
+	}
 	// gen filter using parameter
-	flag.Parse()
+	flag.CommandLine.Parse(args)
+
+	parser := newParser()
+
+	if *listClassifiers {
+		for idx, c := range parser.Classifiers() {
+			fmt.Printf("%d: %s\n", idx, c)
+		}
+		return
+	}
+	if len(*classifyLine) > 0 {
+		if c, ok := parser.Classify(*classifyLine); ok {
+			fmt.Printf("claimed by %s\n", c)
+		} else {
+			fmt.Println("not claimed, parsed as unknown")
+		}
+		return
+	}
+
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{*input}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if sub != nil {
+		sub(ctx, parser, patterns)
+		fmt.Println("DONE")
+		return
+	}
+
+	var (
+		cnt int
+		err error
+		exp *exporter
+		// next as the checkpoint saved once the exports are closed
+		next *logparser.Checkpoint
+	)
+	if len(patterns) == 1 && patterns[0] == "-" {
+		stdin, serr := logparser.NewLogReader(os.Stdin)
+		if serr != nil {
+			panic(serr)
+		}
+		defer stdin.Close()
+
+		exp = newExporter(*output, outNameOr("stdin"), datePart(), *format, false, false)
+		fmt.Println("start parsing stdin ...")
+		cnt, err = parser.ParseReader(ctx, stdin, exp.emit)
+	} else if *follow {
+		if len(patterns) != 1 {
+			panic("only one log can be followed")
+		}
+
+		exp = newExporter(*output, outNameOr(filepath.Base(patterns[0])), datePart(), *format, false, false).following()
+		fmt.Printf("following %s ...\n", patterns[0])
+		opts := logparser.FollowOptions{
+			Poll:      *poll,
+			FromStart: *fromStart,
+		}
+		cnt, err = parser.Follow(ctx, patterns[0], opts, exp.emit)
+		if err == context.Canceled {
+			err = nil
+		}
+	} else if len(*checkpoint) > 0 {
+		if len(patterns) != 1 {
+			panic("only one log can be parsed with a checkpoint")
+		}
+
+		cp, cerr := logparser.LoadCheckpoint(*checkpoint)
+		if cerr != nil {
+			panic(cerr)
+		}
+		exp = newExporter(*output, outNameOr(filepath.Base(patterns[0])), datePart(), *format, false, false)
+		if cp != nil {
+			fmt.Printf("resuming after line %d of %s ...\n", cp.Lines, cp.Path)
+			exp.appending()
+		}
+
+		// the items are held back until the increment parsed in full, so that
+		// the exports and the checkpoint always advance together
+		pending := []logparser.Item{}
+		next, cnt, err = parser.ParseIncremental(ctx, patterns[0], cp, func(i logparser.Item) error {
+			pending = append(pending, i)
+			return nil
+		})
+		if err == nil {
+			for _, i := range pending {
+				if err = exp.emit(i); err != nil {
+					break
+				}
+			}
+		}
+	} else {
+		sets := inputSets(patterns)
+
+		exp = newExporter(*output, outNameOr(mergedName(patterns, sets)), datePart(), *format, *split, countPaths(sets) > 1)
+		fmt.Printf("start parsing %v ...\n", sets)
+		cnt, err = parseSets(ctx, parser, sets, exp.emit)
+	}
+	exp.meta["inputs"] = strings.Join(patterns, " ")
+	exp.meta["date"] = *filterDate
+	exp.meta["lines"] = strconv.Itoa(cnt)
+	exp.meta["parsed_at"] = time.Now().Format(time.RFC3339)
+	if err != nil {
+		// the target keeps pointing at the last complete export
+		exp.abort()
+		panic(err)
+	}
+	if err := exp.close(); err != nil {
+		panic(err)
+	}
+	recordTargetName(strings.Join(exp.outNames, "\n"))
+	if next != nil {
+		if err := next.Save(*checkpoint); err != nil {
+			panic(err)
+		}
+	}
+	fmt.Printf("%d lines successfully parsed\n", cnt)
+	printReport(parser)
+
+	fmt.Println("DONE")
+}
+
+// newParser sets up a parser of the tendermint and benchmark logs as the flags
+// require
+func newParser() *logparser.Parser {
+	parser := logparser.NewParser()
+	if *lenient {
+		parser.SetLenient(*maxErrors)
+		parser.SetKeepMalformed(*keepMalformed)
+	}
 
 	if len(*filterDate) > 0 {
 		date, err := time.Parse("2006-01-02", *filterDate)
@@ -51,44 +240,153 @@ func main() {
 			os.Exit(1)
 		}
 
-		logparser.RegisterItemFilter(func(i logparser.Item) bool {
+		parser.RegisterItemFilter(func(i logparser.Item) bool {
 			iDate := i.Stamp()
 			return (date.Day() == iDate.Day()) && (date.Month() == iDate.Month()) && (date.Year() == iDate.Year())
 		})
-		logparser.SetCurrentHeightStamp(date)
 
 		fmt.Printf("%s as log item data filter added\n", *filterDate)
 	}
 
+	if date := *logDate; len(date) > 0 || len(*filterDate) > 0 {
+		if len(date) == 0 {
+			date = *filterDate
+		}
+		d, err := time.Parse(logparser.CometDateFmt, date)
+		if err != nil {
+			fmt.Printf("log-date: error parsing date: %s", err.Error())
+			os.Exit(1)
+		}
+		parser.TM().SetLogDate(d)
+	}
+
 	// parse as tendermint-like log
-	logparser.RegisterTMPrefix()
+	if err := parser.RegisterTMPrefix(); err != nil {
+		panic(err)
+	}
+	// parse the cometbft log of the plain format
+	if err := parser.RegisterTMComet(); err != nil {
+		panic(err)
+	}
+	// parse the tendermint log of log_format = "json"
+	if err := parser.RegisterTMJSON(); err != nil {
+		panic(err)
+	}
 	// parse the self-made benchmark log
-	logparser.RegisterBSPrefix()
+	if err := parser.RegisterBSPrefix(); err != nil {
+		panic(err)
+	}
+	return parser
+}
 
-	fmt.Printf("start parsing %s ...\n", *input)
-	res, cnt, err := logparser.ParseByLine(*input)
+// parseAll gathers the items of the inputs in memory, returns them along with
+// the name of the outputs
+func parseAll(ctx context.Context, parser *logparser.Parser, patterns []string) (logparser.ParseResult, string) {
+	res := logparser.ParseResult{}
+	outName := parseInto(ctx, parser, patterns, logparser.Collect(res))
+	return res, outName
+}
+
+// parseInto parses the inputs into emit, returns the name of the outputs
+func parseInto(ctx context.Context, parser *logparser.Parser, patterns []string, emit logparser.EmitFunc) string {
+	var (
+		outName string
+		cnt     int
+		err     error
+	)
+	if len(patterns) == 1 && patterns[0] == "-" {
+		stdin, serr := logparser.NewLogReader(os.Stdin)
+		if serr != nil {
+			panic(serr)
+		}
+		defer stdin.Close()
+
+		outName = outNameOr("stdin")
+		fmt.Println("start parsing stdin ...")
+		cnt, err = parser.ParseReader(ctx, stdin, emit)
+	} else {
+		sets := inputSets(patterns)
+
+		outName = outNameOr(mergedName(patterns, sets))
+		fmt.Printf("start parsing %v ...\n", sets)
+		cnt, err = parseSets(ctx, parser, sets, emit)
+	}
 	if err != nil {
 		panic(err)
 	}
 	fmt.Printf("%d lines successfully parsed\n", cnt)
+	printReport(parser)
 
-	datePart := ""
+	return outName
+}
+
+func printReport(parser *logparser.Parser) {
+	if report := parser.Report(); len(report.Errors) > 0 {
+		fmt.Printf("%d malformed lines skipped:\n", len(report.Errors))
+		for _, e := range report.Errors {
+			fmt.Printf("  %s\n", e.Error())
+		}
+	}
+}
+
+func datePart() string {
 	if len(*filterDate) > 0 {
-		datePart = "." + *filterDate
+		return "." + *filterDate
 	}
-	outName := filepath.Base(*input) + datePart
-	recordTargetName(outName)
+	return ""
+}
 
-	fmt.Println("start exporting parsed data ...")
-	for name, data := range res {
-		outFile := path.Join(*output, outName+"."+name+".csv")
+// inputSets expands the globs into the files to parse, each of them a set of
+// its own or with -rotated the set of it together with its rotated files
+func inputSets(patterns []string) [][]string {
+	paths, err := logparser.ExpandInputs(patterns)
+	if err != nil {
+		panic(err)
+	}
 
-		if err := logparser.SaveAsCSV(outFile, data); err != nil {
-			fmt.Printf("error exporting data to %s: %s\n", outFile, err.Error())
+	ret := make([][]string, 0, len(paths))
+	for _, p := range paths {
+		if !*rotated {
+			ret = append(ret, []string{p})
 			continue
 		}
-		fmt.Printf("data successfully exported to %s\n", outFile)
+		set, err := logparser.RotatedSet(p)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("rotated set of %s: %v\n", p, set)
+		ret = append(ret, set)
 	}
+	return ret
+}
 
-	fmt.Println("DONE")
+// parseSets parses the sets of files on the workers the flag asks for
+func parseSets(ctx context.Context, parser *logparser.Parser, sets [][]string, emit logparser.EmitFunc) (int, error) {
+	if *workers > 1 {
+		return parser.ParseFileSetsParallel(ctx, sets, *workers, emit)
+	}
+	return parser.ParseFileSets(ctx, sets, emit)
+}
+
+func countPaths(sets [][]string) int {
+	cnt := 0
+	for _, set := range sets {
+		cnt += len(set)
+	}
+	return cnt
+}
+
+// mergedName names the merged output after the only input, otherwise `merged`
+func mergedName(patterns []string, sets [][]string) string {
+	if len(sets) == 1 {
+		return filepath.Base(patterns[0])
+	}
+	return "merged"
+}
+
+func outNameOr(fallback string) string {
+	if len(*name) > 0 {
+		return *name
+	}
+	return fallback
 }
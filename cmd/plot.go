@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"gonum.org/v1/plot"
+
+	"github.com/tjan147/logparser"
+	"github.com/tjan147/logparser/chart"
+)
+
+// runPlot renders the block costs and the bench costs of the inputs into
+// images named like `NAME.CHART.png`
+func runPlot(ctx context.Context, parser *logparser.Parser, patterns []string) {
+	res, outName := parseAll(ctx, parser, patterns)
+	prefix := path.Join(*output, outName+datePart())
+
+	var saved []string
+	profiles := logparser.BuildBlockProfile(res)
+	if len(profiles) > 0 {
+		blockCost, err := chart.BlockCost(profiles)
+		if err != nil {
+			panic(err)
+		}
+		saved = append(saved, savePlot(blockCost, prefix, "blockCost"))
+
+		stacked, err := chart.StackedCost(profiles)
+		if err != nil {
+			panic(err)
+		}
+		saved = append(saved, savePlot(stacked, prefix, "stackedCost"))
+	}
+
+	for _, class := range chart.BenchClasses(res) {
+		bench, err := chart.BenchCost(class, res[class])
+		if err != nil {
+			panic(err)
+		}
+		saved = append(saved, savePlot(bench, prefix, class))
+	}
+
+	if len(saved) == 0 {
+		fmt.Println("nothing to plot")
+		return
+	}
+	recordTargetName(outName + datePart())
+	fmt.Printf("%d charts plotted\n", len(saved))
+}
+
+// savePlot saves the chart next to the exports, returns the saved path
+func savePlot(p *plot.Plot, prefix, name string) string {
+	out := prefix + "." + name + "." + *plotFormat
+	if err := chart.Save(p, out); err != nil {
+		panic(err)
+	}
+	fmt.Printf("chart successfully plotted to %s\n", out)
+	return out
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tjan147/logparser"
+)
+
+// runProfile exports the per height block profile of the inputs
+func runProfile(ctx context.Context, parser *logparser.Parser, patterns []string) {
+	res, outName := parseAll(ctx, parser, patterns)
+	rows := logparser.BuildBlockProfile(res)
+
+	exp := newExporter(*output, outName, analysisSuffix(logparser.ClassBlockProfile), *format, false, false)
+	for _, row := range rows {
+		if err := exp.emit(row); err != nil {
+			panic(err)
+		}
+	}
+	exp.meta["inputs"] = strings.Join(patterns, " ")
+	exp.meta["date"] = *filterDate
+	exp.meta["heights"] = strconv.Itoa(len(rows))
+	exp.meta["parsed_at"] = time.Now().Format(time.RFC3339)
+	if err := exp.close(); err != nil {
+		panic(err)
+	}
+	recordTargetName(strings.Join(exp.outNames, "\n"))
+
+	fmt.Printf("%d heights profiled\n", len(rows))
+}
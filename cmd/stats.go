@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tjan147/logparser"
+)
+
+// runStats exports the cost statistics of the inputs, the stats of every file
+// are gathered apart and merged, with -split they are exported apart as well
+func runStats(ctx context.Context, parser *logparser.Parser, patterns []string) {
+	perFile := map[string]*logparser.Stats{}
+	outName := parseInto(ctx, parser, patterns, func(item logparser.Item) error {
+		source := ""
+		if src, ok := item.(logparser.Sourced); ok {
+			source = src.Source()
+		}
+		stats, ok := perFile[source]
+		if !ok {
+			stats = logparser.NewStats()
+			perFile[source] = stats
+		}
+		return stats.Emit(item)
+	})
+
+	sources := make([]string, 0, len(perFile))
+	total := logparser.NewStats()
+	for source, stats := range perFile {
+		sources = append(sources, source)
+		total.Merge(stats)
+	}
+	sort.Strings(sources)
+
+	var outNames []string
+	if *split && len(sources) > 1 {
+		for _, source := range sources {
+			outNames = append(outNames, exportStats(filepath.Base(source), perFile[source], patterns)...)
+		}
+	} else {
+		outNames = exportStats(outName, total, patterns)
+	}
+	recordTargetName(strings.Join(outNames, "\n"))
+
+	printStats(total)
+}
+
+func exportStats(outName string, stats *logparser.Stats, patterns []string) []string {
+	exp := newExporter(*output, outName, analysisSuffix(logparser.ClassStats), *format, false, false)
+	for _, row := range stats.Rows() {
+		if err := exp.emit(row); err != nil {
+			panic(err)
+		}
+	}
+	exp.meta["inputs"] = strings.Join(patterns, " ")
+	exp.meta["date"] = *filterDate
+	exp.meta["parsed_at"] = time.Now().Format(time.RFC3339)
+	if err := exp.close(); err != nil {
+		panic(err)
+	}
+	return exp.outNames
+}
+
+// printStats lists the statistics as a table in ms
+func printStats(stats *logparser.Stats) {
+	rows := stats.Rows()
+	if len(rows) == 0 {
+		fmt.Println("no costs found")
+		return
+	}
+
+	header := rows[0].Header()
+	fmt.Println(strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Println(strings.Join(row.Format(), "\t"))
+	}
+}
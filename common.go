@@ -1,11 +1,9 @@
 package logparser
 
 import (
-	"bufio"
 	"encoding/csv"
 	"fmt"
 	"os"
-	"strings"
 	"time"
 )
 
@@ -60,6 +58,8 @@ var _ Item = (*UnknownItem)(nil)
 
 // UnknownItem as the unclassified item data holder
 type UnknownItem struct {
+	itemSource
+
 	data string
 }
 
@@ -81,6 +81,12 @@ func (i *UnknownItem) Format() []string {
 	return []string{i.data}
 }
 
+func (i *UnknownItem) Fields() []Field {
+	return []Field{
+		StringField("data", i.data),
+	}
+}
+
 func (i *UnknownItem) Stamp() time.Time {
 	return time.Time{}
 }
@@ -93,93 +99,6 @@ func (i UnknownItem) Level() ItemLevel {
 	return LevelNone
 }
 
-// --------------- parser ---------------- //
-
-type ParseFunc = func(int, string) (Item, error)
-
-var prefixClassifier = map[string]ParseFunc{}
-
-func RegisterPrefixClassifier(prefix string, parser ParseFunc) error {
-	if _, ok := prefixClassifier[prefix]; ok {
-		return fmt.Errorf("prefix %s already taken", prefix)
-	}
-	prefixClassifier[prefix] = parser
-	return nil
-}
-
-// -------------- filter --------------- //
-
-type FilterFunc = func(Item) bool
-
-var itemFilters = []FilterFunc{}
-
-func RegisterItemFilter(filter FilterFunc) {
-	itemFilters = append(itemFilters, filter)
-}
-
-func GetItemFiltersCount() int {
-	return len(itemFilters)
-}
-
-// -------------- log parsing ---------------- //
-
-type ParseResult = map[string][]Item
-
-func ParseByLine(path string) (ParseResult, int, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, 0, err
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	lineCount := 0
-
-	parsedLogs := ParseResult{}
-	for scanner.Scan() {
-		lineCount++
-		lineText := scanner.Text()
-
-		hit := false
-		var logItem Item
-		for prefix, parser := range prefixClassifier {
-			if strings.HasPrefix(lineText, prefix) {
-				hit = true
-
-				logItem, err = parser(lineCount, lineText)
-				if err != nil {
-					return nil, lineCount, err
-				}
-				break
-			}
-		}
-		if !hit {
-			logItem = NewUnknownItem(lineText)
-		}
-
-		pass := true
-		for _, f := range itemFilters {
-			if !f(logItem) {
-				pass = false
-				break
-			}
-		}
-
-		if pass {
-			holder, ok := parsedLogs[logItem.Class()]
-			if !ok {
-				holder = make([]Item, 0)
-			}
-			parsedLogs[logItem.Class()] = append(holder, logItem)
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, 0, err
-	}
-
-	return parsedLogs, lineCount, nil
-}
-
 // ----------------- utility ---------------- //
 
 func SaveAsCSV(path string, content []Item) error {
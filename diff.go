@@ -0,0 +1,237 @@
+package logparser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// ------------- diff -------------- //
+
+// DiffOptions tells what counts as a regression of a statistic
+type DiffOptions struct {
+	// Quantile as the name of one of the StatQuantiles compared
+	Quantile string
+	// Threshold as the relative growth of the quantile counted as a
+	// regression, 0.1 for 10%
+	Threshold float64
+	// Alpha as the significance level of the Welch's t-test of the means
+	Alpha float64
+}
+
+var DefaultDiffOptions = DiffOptions{
+	Quantile:  "p90",
+	Threshold: 0.1,
+	Alpha:     0.05,
+}
+
+func (o DiffOptions) quantile() (float64, error) {
+	for _, q := range StatQuantiles {
+		if q.Name == o.Quantile {
+			return q.Q, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown quantile %s", o.Quantile)
+}
+
+// welch returns the t statistic and the two-sided p-value of the Welch's
+// t-test of the means of the sketches, a p-value of 1 when either side has
+// less than two durations. Without any variance, common with the costs logged
+// in whole milliseconds, apart means are told with certainty: t is infinite
+// and the p-value 0
+func welch(base, head *Sketch) (float64, float64) {
+	if base.count < 2 || head.count < 2 {
+		return 0, 1
+	}
+	nb, nh := float64(base.count), float64(head.count)
+	vb, vh := base.m2/(nb-1)/nb, head.m2/(nh-1)/nh
+	if vb+vh == 0 {
+		switch {
+		case head.mean > base.mean:
+			return math.Inf(1), 0
+		case head.mean < base.mean:
+			return math.Inf(-1), 0
+		default:
+			return 0, 1
+		}
+	}
+
+	t := (head.mean - base.mean) / math.Sqrt(vb+vh)
+	df := (vb + vh) * (vb + vh) / (vb*vb/(nb-1) + vh*vh/(nh-1))
+	p := 2 * distuv.StudentsT{Mu: 0, Sigma: 1, Nu: df}.Survival(math.Abs(t))
+	return t, p
+}
+
+func relDelta(base, head time.Duration) float64 {
+	if base == 0 {
+		if head == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return float64(head-base) / float64(base)
+}
+
+// ClassStatDiff as the Item.Class() of the compared statistics
+const ClassStatDiff = "statDiff"
+
+var _ Item = (*StatDiff)(nil)
+
+// StatDiff compares one statistic of a base run and a head run, a side
+// missing the statistic has a zero count
+type StatDiff struct {
+	key  StatKey
+	base *StatRow
+	head *StatRow
+
+	t         float64
+	p         float64
+	regressed bool
+}
+
+// Regressed tells whether the quantile grew over the threshold with the
+// means significantly apart
+func (i *StatDiff) Regressed() bool {
+	return i.regressed
+}
+
+func (i *StatDiff) Key() StatKey {
+	return i.key
+}
+
+// CompareStats aligns the statistics of the two runs by metric and group,
+// returns a StatDiff per key of either side in the Keys() order
+func CompareStats(base, head *Stats, opts DiffOptions) ([]*StatDiff, error) {
+	q, err := opts.quantile()
+	if err != nil {
+		return nil, err
+	}
+
+	all := NewStats()
+	for key := range base.sketches {
+		all.sketches[key] = nil
+	}
+	for key := range head.sketches {
+		all.sketches[key] = nil
+	}
+
+	ret := []*StatDiff{}
+	for _, key := range all.Keys() {
+		b, h := base.sketches[key], head.sketches[key]
+		if b == nil {
+			b = NewSketch()
+		}
+		if h == nil {
+			h = NewSketch()
+		}
+
+		d := &StatDiff{
+			key:  key,
+			base: NewStatRow(key, b),
+			head: NewStatRow(key, h),
+		}
+		d.t, d.p = welch(b, h)
+		if b.count > 0 && h.count > 0 {
+			d.regressed = relDelta(b.Quantile(q), h.Quantile(q)) > opts.Threshold && d.t > 0 && d.p < opts.Alpha
+		}
+		ret = append(ret, d)
+	}
+	return ret, nil
+}
+
+func (i *StatDiff) Data() string {
+	parts := []string{i.key.Metric, i.key.Group}
+	for idx, q := range StatQuantiles {
+		parts = append(parts, fmt.Sprintf("%s=%s->%s", q.Name, i.base.quantiles[idx], i.head.quantiles[idx]))
+	}
+	parts = append(parts, fmt.Sprintf("p_value=%.4f", i.p))
+	if i.regressed {
+		parts = append(parts, "REGRESSED")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (i *StatDiff) Header() []string {
+	ret := []string{}
+	for _, f := range i.Fields() {
+		ret = append(ret, f.Name)
+	}
+	return ret
+}
+
+func formatDelta(delta float64) string {
+	if math.IsInf(delta, 1) {
+		return "+inf%"
+	}
+	return fmt.Sprintf("%+.1f%%", delta*100)
+}
+
+// Format gives the durations in fractional milliseconds and the deltas in
+// percents
+func (i *StatDiff) Format() []string {
+	ret := []string{}
+	for _, f := range i.Fields() {
+		switch f.Kind {
+		case FieldDuration:
+			ms := float64(f.Duration()) / float64(time.Millisecond)
+			ret = append(ret, strconv.FormatFloat(ms, 'f', 3, 64))
+		case FieldFloat:
+			if strings.HasSuffix(f.Name, "_delta") {
+				ret = append(ret, formatDelta(f.Float()))
+			} else {
+				ret = append(ret, strconv.FormatFloat(f.Float(), 'f', 4, 64))
+			}
+		default:
+			ret = append(ret, f.String())
+		}
+	}
+	return ret
+}
+
+func (i *StatDiff) Fields() []Field {
+	ret := []Field{
+		StringField("metric", i.key.Metric),
+		StringField("group", i.key.Group),
+		IntField("base_count", i.base.count),
+		IntField("head_count", i.head.count),
+		DurationField("base_mean", i.base.mean),
+		DurationField("head_mean", i.head.mean),
+		FloatField("mean_delta", relDelta(i.base.mean, i.head.mean)),
+	}
+	for idx, q := range StatQuantiles {
+		b, h := i.base.quantiles[idx], i.head.quantiles[idx]
+		ret = append(ret,
+			DurationField("base_"+q.Name, b),
+			DurationField("head_"+q.Name, h),
+			FloatField(q.Name+"_delta", relDelta(b, h)),
+		)
+	}
+	regressed := 0
+	if i.regressed {
+		regressed = 1
+	}
+	return append(ret,
+		FloatField("t", i.t),
+		FloatField("p_value", i.p),
+		IntField("regressed", regressed),
+	)
+}
+
+func (i *StatDiff) Stamp() time.Time {
+	return time.Time{}
+}
+
+func (i StatDiff) Class() string {
+	return ClassStatDiff
+}
+
+func (i *StatDiff) Level() ItemLevel {
+	if i.regressed {
+		return LevelWarn
+	}
+	return LevelInfo
+}
@@ -0,0 +1,76 @@
+package logparser
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func sketchOf(values ...time.Duration) *Sketch {
+	sk := NewSketch()
+	for _, d := range values {
+		sk.Add(d)
+	}
+	return sk
+}
+
+func repeat(d time.Duration, n int) []time.Duration {
+	ret := make([]time.Duration, n)
+	for idx := range ret {
+		ret[idx] = d
+	}
+	return ret
+}
+
+func TestWelch(t *testing.T) {
+	ms := time.Millisecond
+	cases := []struct {
+		name  string
+		base  *Sketch
+		head  *Sketch
+		t     float64
+		p     float64
+		delta float64
+	}{
+		{"too few", sketchOf(ms), sketchOf(2*ms, 3*ms), 0, 1, 0},
+		{"constant equal", sketchOf(repeat(ms, 100)...), sketchOf(repeat(ms, 100)...), 0, 1, 0},
+		{"constant grown", sketchOf(repeat(ms, 10000)...), sketchOf(repeat(11*ms, 10000)...), math.Inf(1), 0, 0},
+		{"constant shrunk", sketchOf(repeat(11*ms, 100)...), sketchOf(repeat(ms, 100)...), math.Inf(-1), 0, 0},
+		// t = 3.490 with 9.78 degrees of freedom
+		{"apart", sketchOf(20*ms, 22*ms, 19*ms, 20*ms, 22*ms, 18*ms), sketchOf(24*ms, 25*ms, 22*ms, 26*ms, 21*ms, 24*ms), 3.490, 0.0060, 0.001},
+		{"same spread", sketchOf(ms, 2*ms, 3*ms), sketchOf(ms, 2*ms, 3*ms), 0, 1, 1e-9},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gotT, gotP := welch(c.base, c.head)
+			if math.IsInf(c.t, 0) {
+				if gotT != c.t {
+					t.Fatalf("t %v, want %v", gotT, c.t)
+				}
+			} else if math.Abs(gotT-c.t) > c.delta+0.01 {
+				t.Fatalf("t %v, want %v", gotT, c.t)
+			}
+			if math.Abs(gotP-c.p) > c.delta+1e-9 {
+				t.Fatalf("p %v, want %v", gotP, c.p)
+			}
+		})
+	}
+}
+
+func TestCompareStatsConstantRegression(t *testing.T) {
+	key := StatKey{Metric: "tmQuerier.querier_cost", Group: "path=/custom/acc"}
+	base, head := NewStats(), NewStats()
+	for i := 0; i < 10000; i++ {
+		base.add(key.Metric, key.Group, time.Millisecond)
+		head.add(key.Metric, key.Group, 11*time.Millisecond)
+	}
+
+	diffs, err := CompareStats(base, head, DefaultDiffOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || !diffs[0].Regressed() {
+		t.Fatalf("constant 1ms to 11ms not regressed: %v", diffs)
+	}
+}
@@ -0,0 +1,210 @@
+package logparser
+
+import (
+	"strconv"
+	"time"
+)
+
+// FieldKind as the enum def of the Field value types
+type FieldKind int8
+
+// enum value
+const (
+	FieldString FieldKind = iota
+	FieldInt
+	FieldDuration
+	FieldTime
+	FieldFloat
+)
+
+// Str as the pretty serialzation
+func (k FieldKind) Str() string {
+	switch k {
+	case FieldString:
+		return "string"
+	case FieldInt:
+		return "int"
+	case FieldDuration:
+		return "duration"
+	case FieldTime:
+		return "time"
+	case FieldFloat:
+		return "float"
+	default:
+	}
+	return "unknown"
+}
+
+// Field as a named typed value of an item, the Value holds a string, an int,
+// a time.Duration, a time.Time or a float64 according to the Kind, nil for a
+// value which is not known
+type Field struct {
+	Name  string
+	Kind  FieldKind
+	Value interface{}
+}
+
+func StringField(name, v string) Field {
+	return Field{Name: name, Kind: FieldString, Value: v}
+}
+
+func IntField(name string, v int) Field {
+	return Field{Name: name, Kind: FieldInt, Value: v}
+}
+
+func DurationField(name string, v time.Duration) Field {
+	return Field{Name: name, Kind: FieldDuration, Value: v}
+}
+
+func TimeField(name string, v time.Time) Field {
+	return Field{Name: name, Kind: FieldTime, Value: v}
+}
+
+func FloatField(name string, v float64) Field {
+	return Field{Name: name, Kind: FieldFloat, Value: v}
+}
+
+// NullField as a field of the kind whose value is not known, exported as an
+// empty csv cell, a JSON null or a NULL
+func NullField(name string, kind FieldKind) Field {
+	return Field{Name: name, Kind: kind}
+}
+
+// IsNull tells whether the value of the field is not known
+func (f Field) IsNull() bool {
+	return f.Value == nil
+}
+
+// Int returns the value of an int field, 0 for the other kinds
+func (f Field) Int() int {
+	v, _ := f.Value.(int)
+	return v
+}
+
+// Duration returns the value of a duration field, 0 for the other kinds
+func (f Field) Duration() time.Duration {
+	v, _ := f.Value.(time.Duration)
+	return v
+}
+
+// Time returns the value of a time field, the zero time for the other kinds
+func (f Field) Time() time.Time {
+	v, _ := f.Value.(time.Time)
+	return v
+}
+
+// Float returns the value of a float field, 0 for the other kinds
+func (f Field) Float() float64 {
+	v, _ := f.Value.(float64)
+	return v
+}
+
+// String returns the value formatted the way Item.Format() does
+func (f Field) String() string {
+	switch v := f.Value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case time.Duration:
+		return strconv.FormatInt(v.Milliseconds(), 10)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+	}
+	return ""
+}
+
+// Fielder is implemented by the items exposing their data as typed fields
+type Fielder interface {
+	Fields() []Field
+}
+
+// FieldOf returns the named field of the item, false if the item has none
+func FieldOf(item Item, name string) (Field, bool) {
+	fielder, ok := item.(Fielder)
+	if !ok {
+		return Field{}, false
+	}
+	for _, f := range fielder.Fields() {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Column as a Header() column typed by the field of the same name
+type Column struct {
+	Name string
+	Kind FieldKind
+}
+
+// ColumnsOf types the Header() columns of the item by its fields, a column
+// without a field of the same name is a string
+func ColumnsOf(item Item) []Column {
+	kinds := map[string]FieldKind{}
+	if fielder, ok := item.(Fielder); ok {
+		for _, f := range fielder.Fields() {
+			kinds[f.Name] = f.Kind
+		}
+	}
+
+	header := item.Header()
+	ret := make([]Column, 0, len(header))
+	for _, name := range header {
+		kind, ok := kinds[name]
+		if !ok {
+			kind = FieldString
+		}
+		ret = append(ret, Column{Name: name, Kind: kind})
+	}
+	return ret
+}
+
+// RowOf returns the typed values of the Header() columns of the item, the
+// formatted value stands in for a column without a field
+func RowOf(item Item) []Field {
+	fields := map[string]Field{}
+	if fielder, ok := item.(Fielder); ok {
+		for _, f := range fielder.Fields() {
+			fields[f.Name] = f
+		}
+	}
+
+	header := item.Header()
+	formatted := item.Format()
+	ret := make([]Field, 0, len(header))
+	for idx, name := range header {
+		if f, ok := fields[name]; ok {
+			ret = append(ret, f)
+		} else if idx < len(formatted) {
+			ret = append(ret, StringField(name, formatted[idx]))
+		} else {
+			ret = append(ret, StringField(name, ""))
+		}
+	}
+	return ret
+}
+
+var (
+	_ Fielder = (*UnknownItem)(nil)
+	_ Fielder = (*MalformedItem)(nil)
+	_ Fielder = (*benchStoreItem)(nil)
+	_ Fielder = (*TMItemErr)(nil)
+	_ Fielder = (*TMInfoApply)(nil)
+	_ Fielder = (*TMInfoCommit)(nil)
+	_ Fielder = (*TMInfoEndBlocker)(nil)
+	_ Fielder = (*TMInfoHandler)(nil)
+	_ Fielder = (*TMInfoQuerier)(nil)
+	_ Fielder = (*TMInfoIgnore)(nil)
+	_ Fielder = (*TMInfoConsensus)(nil)
+	_ Fielder = (*BlockProfile)(nil)
+	_ Fielder = (*StatRow)(nil)
+	_ Fielder = (*RecordItem)(nil)
+	_ Fielder = (*StatDiff)(nil)
+	_ Fielder = (*Anomaly)(nil)
+	_ Fielder = (*BlockTimeline)(nil)
+)
@@ -0,0 +1,237 @@
+package logparser
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultFollowPoll as the default interval between checks for new lines
+const DefaultFollowPoll = 500 * time.Millisecond
+
+// followBatch as the most lines parsed before the followed log is checked for
+// rotation again, so that a backlog does not hold the checks off
+const followBatch = 4096
+
+// FollowOptions tunes Follow
+type FollowOptions struct {
+	// Poll as the interval between checks for new lines
+	Poll time.Duration
+	// FromStart parses the existing content first instead of only the lines
+	// appended from now on
+	FromStart bool
+}
+
+// followedFile as the file currently tailed together with the read position
+type followedFile struct {
+	file    *os.File
+	info    os.FileInfo
+	reader  *bufio.Reader
+	offset  int64
+	lineNum int
+	pending string
+}
+
+func openFollowed(path string, fromStart bool) (*followedFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	f := &followedFile{
+		file:   file,
+		info:   info,
+		reader: bufio.NewReader(file),
+	}
+	if !fromStart {
+		// skip the existing complete lines, counting them so that the line
+		// numbers of the new ones stay right
+		if err := f.skip(); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (f *followedFile) skip() error {
+	for {
+		text, err := f.reader.ReadString('\n')
+		if err == io.EOF {
+			// leave a partial last line to be completed later
+			if _, err := f.file.Seek(f.offset, io.SeekStart); err != nil {
+				return err
+			}
+			f.reader.Reset(f.file)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		f.offset += int64(len(text))
+		f.lineNum++
+	}
+}
+
+// next returns the next complete line, false when none is available yet
+func (f *followedFile) next() (string, bool, error) {
+	text, err := f.reader.ReadString('\n')
+	f.offset += int64(len(text))
+	if err == io.EOF {
+		f.pending += text
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	line := strings.TrimRight(f.pending+text, "\r\n")
+	f.pending = ""
+	f.lineNum++
+	return line, true, nil
+}
+
+func (f *followedFile) rewind() error {
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	f.reader.Reset(f.file)
+	f.offset = 0
+	f.lineNum = 0
+	f.pending = ""
+	return nil
+}
+
+// Follow tails the log at path like `tail -F` and emits the items of the new
+// lines as they are written, until ctx is done. A log rotated away is read to
+// its end before the new file at path is followed from its start, a truncated
+// log is read again from its start
+func (p *Parser) Follow(ctx context.Context, path string, opts FollowOptions, emit EmitFunc) (int, error) {
+	if opts.Poll <= 0 {
+		opts.Poll = DefaultFollowPoll
+	}
+	p.report = &ParseReport{}
+	p.source = path
+	defer func() {
+		p.source = ""
+	}()
+
+	total := 0
+	var cur *followedFile
+	defer func() {
+		if cur != nil {
+			cur.file.Close()
+		}
+	}()
+
+	fromStart := opts.FromStart
+	for {
+		if cur == nil {
+			f, err := openFollowed(path, fromStart)
+			if err != nil && !os.IsNotExist(err) {
+				return total, err
+			}
+			// a file showing up later is read from its start
+			fromStart = true
+			cur = f
+		}
+
+		more := false
+		if cur != nil {
+			cnt, backlog, err := p.drainFollowed(ctx, cur, emit)
+			total += cnt
+			if err != nil {
+				return total, err
+			}
+			more = backlog
+
+			rotated, err := checkFollowed(cur, path)
+			if err != nil {
+				return total, err
+			}
+			if rotated {
+				// catch the lines written before the writer switched over
+				for more = true; more && err == nil; {
+					var cnt int
+					cnt, more, err = p.drainFollowed(ctx, cur, emit)
+					total += cnt
+				}
+				if err == nil && len(cur.pending) > 0 {
+					total++
+					cur.lineNum++
+					err = p.handleLine(cur.lineNum, strings.TrimRight(cur.pending, "\r"), emit)
+				}
+				cur.file.Close()
+				cur = nil
+				if err != nil {
+					return total, err
+				}
+				continue
+			}
+		}
+
+		if more {
+			// go on with the backlog without waiting
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		case <-time.After(opts.Poll):
+		}
+	}
+}
+
+// drainFollowed parses the complete lines available up to followBatch of them,
+// reports whether more lines may be waiting. It stops as soon as ctx is done
+func (p *Parser) drainFollowed(ctx context.Context, cur *followedFile, emit EmitFunc) (int, bool, error) {
+	cnt := 0
+	for cnt < followBatch {
+		select {
+		case <-ctx.Done():
+			return cnt, false, ctx.Err()
+		default:
+		}
+
+		line, ok, err := cur.next()
+		if err != nil || !ok {
+			return cnt, false, err
+		}
+		cnt++
+		if err := p.handleLine(cur.lineNum, line, emit); err != nil {
+			return cnt, false, err
+		}
+	}
+	return cnt, true, nil
+}
+
+// checkFollowed rewinds a truncated file, and reports whether the file was
+// rotated away
+func checkFollowed(cur *followedFile, path string) (bool, error) {
+	info, err := cur.file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() < cur.offset {
+		return false, cur.rewind()
+	}
+
+	latest, err := os.Stat(path)
+	if err != nil {
+		// keep waiting on the current file until a new one shows up
+		return false, nil
+	}
+	return !os.SameFile(cur.info, latest), nil
+}
+
+func Follow(ctx context.Context, path string, opts FollowOptions, emit EmitFunc) (int, error) {
+	return defaultParser.Follow(ctx, path, opts, emit)
+}
@@ -0,0 +1,50 @@
+package logparser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFollowCanceledInBacklog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.log")
+	line := "I[2020-05-12|10:00:00.000] Query Time module=main path=[/custom/acc] cost=1ms\n"
+	if err := os.WriteFile(path, []byte(strings.Repeat(line, 3*followBatch)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewParser()
+	if err := p.RegisterTMPrefix(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	emitted := 0
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Follow(ctx, path, FollowOptions{Poll: time.Hour, FromStart: true}, func(Item) error {
+			emitted++
+			if emitted == 10 {
+				cancel()
+			}
+			return nil
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("follow returned %v, want canceled", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("follow kept draining after the cancel")
+	}
+	if emitted != 10 {
+		t.Fatalf("%d items emitted, want the 10 before the cancel", emitted)
+	}
+}
@@ -0,0 +1,362 @@
+// Package htmlreport writes a parse result into one self-contained static
+// HTML page: the summary stats, the charts, the slowest heights, the errors
+// and a table per class
+package htmlreport
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"gonum.org/v1/plot"
+
+	"github.com/tjan147/logparser"
+	"github.com/tjan147/logparser/chart"
+)
+
+const (
+	DefaultTopHeights = 20
+	DefaultMaxRows    = 100
+)
+
+// Options tunes the report
+type Options struct {
+	Title string
+	// Inputs as the parsed logs listed in the header
+	Inputs []string
+	// TopHeights as the count of the slowest heights listed
+	TopHeights int
+	// MaxRows as the rows shown of every class table, the rest is only counted
+	MaxRows int
+}
+
+type table struct {
+	Header []string
+	Rows   [][]string
+}
+
+type classTable struct {
+	table
+	Class string
+	Total int
+}
+
+type image struct {
+	Title string
+	Data  template.URL
+}
+
+type errorRow struct {
+	Anchor  string
+	Source  string
+	Link    template.URL
+	Line    int
+	Kind    string
+	Message string
+	Raw     string
+}
+
+type page struct {
+	Title     string
+	Generated string
+	Inputs    []string
+	Lines     int
+	Items     int
+	Classes   []classTable
+	Stats     table
+	Charts    []image
+	Slowest   table
+	Errors    []errorRow
+}
+
+func tableOf(items []logparser.Item, limit int) table {
+	ret := table{}
+	if len(items) == 0 {
+		return ret
+	}
+	ret.Header = items[0].Header()
+	for idx, item := range items {
+		if limit > 0 && idx >= limit {
+			break
+		}
+		ret.Rows = append(ret.Rows, item.Format())
+	}
+	return ret
+}
+
+// addChart embeds the chart into the page as a svg data uri
+func (pg *page) addChart(title string, p *plot.Plot, err error) error {
+	if err != nil {
+		return err
+	}
+	svg, err := chart.Encode(p, "svg")
+	if err != nil {
+		return fmt.Errorf("error render %s: %s", title, err.Error())
+	}
+	uri := "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(svg)
+	pg.Charts = append(pg.Charts, image{Title: title, Data: template.URL(uri)})
+	return nil
+}
+
+// slowest returns the profiles with the highest block cost
+func slowest(profiles []logparser.Item, top int) []logparser.Item {
+	sorted := make([]logparser.Item, len(profiles))
+	copy(sorted, profiles)
+	cost := func(item logparser.Item) time.Duration {
+		f, _ := logparser.FieldOf(item, "block_cost")
+		return f.Duration()
+	}
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return cost(sorted[a]) > cost(sorted[b])
+	})
+	if len(sorted) > top {
+		sorted = sorted[:top]
+	}
+	return sorted
+}
+
+// anchors names the anchor of every error line, so that a line can be linked
+// to as `report.html#L123`, or `report.html#S1L123` when several sources were
+// parsed
+type anchors struct {
+	sources map[string]int
+}
+
+func (a *anchors) of(source string, line int) string {
+	idx, ok := a.sources[source]
+	if !ok {
+		idx = len(a.sources)
+		a.sources[source] = idx
+	}
+	if idx == 0 {
+		return "L" + strconv.Itoa(line)
+	}
+	return "S" + strconv.Itoa(idx) + "L" + strconv.Itoa(line)
+}
+
+func fileLink(source string) template.URL {
+	if len(source) == 0 {
+		return ""
+	}
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		abs = source
+	}
+	return template.URL("file://" + filepath.ToSlash(abs))
+}
+
+func errorRows(res logparser.ParseResult, rep *logparser.ParseReport) []errorRow {
+	ret := []errorRow{}
+	for class, items := range res {
+		for _, item := range items {
+			if item.Level() != logparser.LevelErr || class == logparser.ClassMalformed {
+				continue
+			}
+			row := errorRow{Kind: class, Message: item.Data()}
+			if src, ok := item.(logparser.Sourced); ok {
+				row.Source, row.Line = src.Source(), src.Line()
+			}
+			ret = append(ret, row)
+		}
+	}
+	if rep != nil {
+		for _, e := range rep.Errors {
+			ret = append(ret, errorRow{
+				Source:  e.Source,
+				Line:    e.Line,
+				Kind:    logparser.ClassMalformed,
+				Message: e.Err.Error(),
+				Raw:     e.Raw,
+			})
+		}
+	}
+
+	sort.SliceStable(ret, func(a, b int) bool {
+		if ret[a].Source != ret[b].Source {
+			return ret[a].Source < ret[b].Source
+		}
+		return ret[a].Line < ret[b].Line
+	})
+	a := &anchors{sources: map[string]int{}}
+	for idx := range ret {
+		ret[idx].Anchor = a.of(ret[idx].Source, ret[idx].Line)
+		ret[idx].Link = fileLink(ret[idx].Source)
+	}
+	return ret
+}
+
+// Write renders the report of res, rep as the report of the parse and may be
+// nil
+func Write(w io.Writer, res logparser.ParseResult, rep *logparser.ParseReport, opts Options) error {
+	if opts.TopHeights <= 0 {
+		opts.TopHeights = DefaultTopHeights
+	}
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = DefaultMaxRows
+	}
+
+	pg := page{
+		Title:     opts.Title,
+		Generated: time.Now().Format(time.RFC3339),
+		Inputs:    opts.Inputs,
+	}
+	if rep != nil {
+		pg.Lines, pg.Items = rep.Lines, rep.Items
+	}
+
+	classes := make([]string, 0, len(res))
+	for class := range res {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		pg.Classes = append(pg.Classes, classTable{
+			table: tableOf(res[class], opts.MaxRows),
+			Class: class,
+			Total: len(res[class]),
+		})
+	}
+
+	stats := logparser.NewStats()
+	for _, items := range res {
+		for _, item := range items {
+			stats.Add(item)
+		}
+	}
+	pg.Stats = tableOf(stats.Rows(), 0)
+
+	profiles := logparser.BuildBlockProfile(res)
+	if len(profiles) > 0 {
+		p, err := chart.BlockCost(profiles)
+		if err := pg.addChart("block cost", p, err); err != nil {
+			return err
+		}
+		p, err = chart.StackedCost(profiles)
+		if err := pg.addChart("stacked cost", p, err); err != nil {
+			return err
+		}
+		p, err = chart.ModuleCost(res)
+		if err := pg.addChart("endblocker cost by module", p, err); err != nil {
+			return err
+		}
+
+		pg.Slowest = tableOf(slowest(profiles, opts.TopHeights), 0)
+	}
+	for _, class := range chart.BenchClasses(res) {
+		p, err := chart.BenchCost(class, res[class])
+		if err := pg.addChart(class+" cost", p, err); err != nil {
+			return err
+		}
+	}
+
+	pg.Errors = errorRows(res, rep)
+
+	return reportTmpl.Execute(w, pg)
+}
+
+// Save writes the report into the file at path
+func Save(path string, res logparser.ParseResult, rep *logparser.ParseReport, opts Options) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(file, res, rep, opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+var reportTmpl = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; font-size: 13px; }
+th, td { border: 1px solid #ccc; padding: 3px 8px; text-align: left; }
+th { background: #f0f0f0; }
+tr:target { background: #fff3b0; }
+img { width: 100%; max-width: 1400px; }
+.raw { font-family: monospace; white-space: pre-wrap; }
+.note { color: #777; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="note">generated at {{.Generated}}</p>
+<ul>
+{{- range .Inputs}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+
+<h2>Summary</h2>
+<table>
+<tr><th>lines</th><td>{{.Lines}}</td></tr>
+<tr><th>items</th><td>{{.Items}}</td></tr>
+<tr><th>errors</th><td>{{len .Errors}}</td></tr>
+{{- range .Classes}}
+<tr><th>{{.Class}}</th><td>{{.Total}}</td></tr>
+{{- end}}
+</table>
+
+{{- if .Stats.Rows}}
+<h2>Cost statistics (ms)</h2>
+<table>
+<tr>{{range .Stats.Header}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Stats.Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- range .Charts}}
+<h2>{{.Title}}</h2>
+<img src="{{.Data}}" alt="{{.Title}}">
+{{- end}}
+
+{{- if .Slowest.Rows}}
+<h2>Slowest heights</h2>
+<table>
+<tr>{{range .Slowest.Header}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Slowest.Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+
+<h2>Errors</h2>
+{{- if .Errors}}
+<table>
+<tr><th>source</th><th>line</th><th>kind</th><th>message</th><th>raw</th></tr>
+{{- range .Errors}}
+<tr id="{{.Anchor}}"><td>{{if .Link}}<a href="{{.Link}}">{{.Source}}</a>{{end}}</td><td><a href="#{{.Anchor}}">{{.Line}}</a></td><td>{{.Kind}}</td><td>{{.Message}}</td><td class="raw">{{.Raw}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="note">no errors</p>
+{{- end}}
+
+{{- range .Classes}}
+<h2 id="class-{{.Class}}">{{.Class}}</h2>
+{{- if gt .Total (len .Rows)}}
+<p class="note">the first {{len .Rows}} of {{.Total}} rows</p>
+{{- end}}
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package logparser
+
+import "os"
+
+// fileInode is not available on windows, a checkpoint then only relies on the
+// offset and the file size
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,135 @@
+package logparser
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicBzip2 = []byte("BZh")
+)
+
+func isCompressed(magic []byte) bool {
+	return bytes.HasPrefix(magic, magicGzip) || bytes.HasPrefix(magic, magicZstd) || bytes.HasPrefix(magic, magicBzip2)
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (rc *readCloser) Close() error {
+	return rc.close()
+}
+
+// NewLogReader detects gzip, zstd and bzip2 content of r by the magic bytes and
+// decompresses it on the fly, other content is read as it is. Closing the
+// returned reader does not close r
+func NewLogReader(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, magicGzip):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("error open gzip stream: %s", err.Error())
+		}
+		return gz, nil
+	case bytes.HasPrefix(magic, magicZstd):
+		zs, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("error open zstd stream: %s", err.Error())
+		}
+		return zs.IOReadCloser(), nil
+	case bytes.HasPrefix(magic, magicBzip2):
+		return ioutil.NopCloser(bzip2.NewReader(buffered)), nil
+	default:
+	}
+	return ioutil.NopCloser(buffered), nil
+}
+
+// OpenLog opens the log file at path, decompressing it when needed
+func OpenLog(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := NewLogReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error open %s: %s", path, err.Error())
+	}
+
+	return &readCloser{
+		Reader: r,
+		close: func() error {
+			r.Close()
+			return file.Close()
+		},
+	}, nil
+}
+
+// ------------- rotated set -------------- //
+
+// like `.1`, `.2.gz`, `.3.zst`
+var rotatedSuffix = regexp.MustCompile(`^\.(\d+)(\.[[:alnum:]]+)?$`)
+
+// RotatedSet lists path together with its rotated files, oldest first: the
+// set of `node.log` is ordered like `node.log.2.gz`, `node.log.1`, `node.log`
+func RotatedSet(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	type rotated struct {
+		path string
+		idx  int
+	}
+	found := make([]rotated, 0, len(matches))
+	for _, m := range matches {
+		sub := rotatedSuffix.FindStringSubmatch(m[len(path):])
+		if sub == nil {
+			continue
+		}
+		idx, err := strconv.Atoi(sub[1])
+		if err != nil {
+			continue
+		}
+		found = append(found, rotated{path: m, idx: idx})
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].idx > found[j].idx
+	})
+
+	ret := make([]string, 0, len(found)+1)
+	for _, r := range found {
+		ret = append(ret, r.path)
+	}
+	if _, err := os.Stat(path); err == nil {
+		ret = append(ret, path)
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("no log found at %s", path)
+	}
+	return ret, nil
+}
@@ -0,0 +1,361 @@
+package logparser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JSONRecord as the JSON form of an item, durations are given in
+// milliseconds and times in RFC3339 with nanoseconds, a value not known is
+// null
+type JSONRecord struct {
+	Class  string                 `json:"class"`
+	Level  string                 `json:"level,omitempty"`
+	Stamp  string                 `json:"stamp,omitempty"`
+	Source string                 `json:"source,omitempty"`
+	Line   int                    `json:"line,omitempty"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+func jsonValue(f Field) interface{} {
+	switch v := f.Value.(type) {
+	case time.Duration:
+		return float64(v) / float64(time.Millisecond)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		// JSON has no infinities
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil
+		}
+	default:
+	}
+	return f.Value
+}
+
+// NewJSONRecord converts the item, an item without typed fields keeps its
+// formatted columns as strings
+func NewJSONRecord(item Item) *JSONRecord {
+	rec := &JSONRecord{
+		Class:  item.Class(),
+		Level:  item.Level().Str(),
+		Fields: map[string]interface{}{},
+	}
+	if stamp := item.Stamp(); !stamp.IsZero() {
+		rec.Stamp = stamp.Format(time.RFC3339Nano)
+	}
+	if src, ok := item.(Sourced); ok {
+		rec.Source = src.Source()
+		rec.Line = src.Line()
+	}
+
+	if fielder, ok := item.(Fielder); ok {
+		for _, f := range fielder.Fields() {
+			rec.Fields[f.Name] = jsonValue(f)
+		}
+	} else {
+		values := item.Format()
+		for idx, name := range item.Header() {
+			if idx < len(values) {
+				rec.Fields[name] = values[idx]
+			}
+		}
+	}
+	return rec
+}
+
+// JSONLSink streams items as JSON lines, one object per item
+type JSONLSink struct {
+	out  *bufio.Writer
+	enc  *json.Encoder
+	file *os.File
+}
+
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	out := bufio.NewWriter(w)
+	return &JSONLSink{
+		out: out,
+		enc: json.NewEncoder(out),
+	}
+}
+
+// CreateJSONLSink opens the file at path for the sink, either truncated or
+// appended to
+func CreateJSONLSink(path string, appendTo bool) (*JSONLSink, error) {
+	mode := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendTo {
+		mode = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, mode, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewJSONLSink(file)
+	s.file = file
+	return s, nil
+}
+
+// Emit as the EmitFunc writing the item as one line
+func (s *JSONLSink) Emit(item Item) error {
+	return s.enc.Encode(NewJSONRecord(item))
+}
+
+func (s *JSONLSink) Flush() error {
+	return s.out.Flush()
+}
+
+// Close flushes the sink, closing the file opened by CreateJSONLSink
+func (s *JSONLSink) Close() error {
+	err := s.out.Flush()
+	if s.file != nil {
+		if cerr := s.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func SaveAsJSONL(path string, content []Item) error {
+	s, err := CreateJSONLSink(path, false)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range content {
+		if err := s.Emit(item); err != nil {
+			s.Close()
+			return err
+		}
+	}
+	return s.Close()
+}
+
+// ------------- reading back -------------- //
+
+// recordPrototypes type the fields of the records read back, the bench
+// classes are named after the method so they are told by the backend field
+var recordPrototypes = []Item{
+	&TMItemErr{},
+	&TMInfoApply{},
+	&TMInfoCommit{},
+	&TMInfoEndBlocker{},
+	&TMInfoHandler{},
+	&TMInfoQuerier{},
+	&TMInfoIgnore{},
+	&TMInfoConsensus{},
+	&BlockProfile{},
+	&MalformedItem{},
+	&UnknownItem{},
+	NewStatRow(StatKey{}, NewSketch()),
+	&Anomaly{},
+	&BlockTimeline{costs: make([]time.Duration, len(timelineCosts))},
+}
+
+var benchPrototype Item = &benchStoreItem{}
+
+// prototypeOf returns the item of the class of the record, nil if unknown
+func prototypeOf(rec *JSONRecord) Item {
+	for _, proto := range recordPrototypes {
+		if proto.Class() == rec.Class {
+			return proto
+		}
+	}
+	if _, ok := rec.Fields["backend"]; ok {
+		return benchPrototype
+	}
+	return nil
+}
+
+func parseLevel(level string) ItemLevel {
+	for l := LevelNone; l <= LevelErr; l++ {
+		if l.Str() == level {
+			return l
+		}
+	}
+	return LevelNone
+}
+
+// recordField types the JSON value as the kind, numbers of an unknown kind are
+// ints if integral and floats otherwise
+func recordField(name string, kind FieldKind, known bool, v interface{}) (Field, error) {
+	switch val := v.(type) {
+	case nil:
+		if kind == FieldFloat {
+			return FloatField(name, math.NaN()), nil
+		}
+		if known {
+			return NullField(name, kind), nil
+		}
+		return StringField(name, ""), nil
+	case json.Number:
+		if !known {
+			if i, err := val.Int64(); err == nil {
+				return IntField(name, int(i)), nil
+			}
+			kind = FieldFloat
+		}
+		fv, err := val.Float64()
+		if err != nil {
+			return Field{}, fmt.Errorf("error parse %s(%s): %s", name, val, err.Error())
+		}
+		switch kind {
+		case FieldInt:
+			return IntField(name, int(fv)), nil
+		case FieldDuration:
+			return DurationField(name, time.Duration(fv*float64(time.Millisecond))), nil
+		case FieldFloat:
+			return FloatField(name, fv), nil
+		default:
+		}
+		return StringField(name, val.String()), nil
+	case string:
+		if kind == FieldTime {
+			t, err := time.Parse(time.RFC3339Nano, val)
+			if err != nil {
+				return Field{}, fmt.Errorf("error parse %s(%s): %s", name, val, err.Error())
+			}
+			return TimeField(name, t), nil
+		}
+		return StringField(name, val), nil
+	default:
+	}
+	return StringField(name, fmt.Sprint(v)), nil
+}
+
+var _ Item = (*RecordItem)(nil)
+
+// RecordItem as an item read back from a JSONRecord, the fields of the
+// classes this package parses get their kinds back
+type RecordItem struct {
+	itemSource
+
+	class  string
+	level  ItemLevel
+	stamp  time.Time
+	fields []Field
+}
+
+// NewRecordItem converts the record back into an item
+func NewRecordItem(rec *JSONRecord) (*RecordItem, error) {
+	item := &RecordItem{
+		class: rec.Class,
+		level: parseLevel(rec.Level),
+	}
+	if len(rec.Stamp) > 0 {
+		stamp, err := time.Parse(time.RFC3339Nano, rec.Stamp)
+		if err != nil {
+			return nil, fmt.Errorf("error parse stamp(%s): %s", rec.Stamp, err.Error())
+		}
+		item.stamp = stamp
+	}
+	item.setSource(rec.Source, rec.Line)
+
+	// the known fields keep the order of the prototype, the rest follows
+	// sorted by name
+	names := []string{}
+	kinds := map[string]FieldKind{}
+	if fielder, ok := prototypeOf(rec).(Fielder); ok {
+		for _, f := range fielder.Fields() {
+			if _, ok := rec.Fields[f.Name]; ok {
+				names = append(names, f.Name)
+				kinds[f.Name] = f.Kind
+			}
+		}
+	}
+	rest := []string{}
+	for name := range rec.Fields {
+		if _, ok := kinds[name]; !ok {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+
+	for _, name := range append(names, rest...) {
+		kind, known := kinds[name]
+		f, err := recordField(name, kind, known, rec.Fields[name])
+		if err != nil {
+			return nil, err
+		}
+		item.fields = append(item.fields, f)
+	}
+	return item, nil
+}
+
+func (i *RecordItem) Data() string {
+	parts := []string{i.class}
+	for _, f := range i.fields {
+		parts = append(parts, f.Name+"="+f.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func (i *RecordItem) Header() []string {
+	ret := make([]string, 0, len(i.fields))
+	for _, f := range i.fields {
+		ret = append(ret, f.Name)
+	}
+	return ret
+}
+
+func (i *RecordItem) Format() []string {
+	ret := make([]string, 0, len(i.fields))
+	for _, f := range i.fields {
+		ret = append(ret, f.String())
+	}
+	return ret
+}
+
+func (i *RecordItem) Fields() []Field {
+	return i.fields
+}
+
+func (i *RecordItem) Stamp() time.Time {
+	return i.stamp
+}
+
+func (i *RecordItem) Class() string {
+	return i.class
+}
+
+func (i *RecordItem) Level() ItemLevel {
+	return i.level
+}
+
+// ReadJSONL reads the JSON lines written by a JSONLSink back as RecordItems,
+// returns the number of lines read
+func ReadJSONL(r io.Reader, emit EmitFunc) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxLineSize)
+	lineCount := 0
+
+	for scanner.Scan() {
+		lineCount++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		dec.UseNumber()
+		rec := &JSONRecord{}
+		if err := dec.Decode(rec); err != nil {
+			return lineCount, fmt.Errorf("[%d] error decode record: %s", lineCount, err.Error())
+		}
+		item, err := NewRecordItem(rec)
+		if err != nil {
+			return lineCount, fmt.Errorf("[%d] %s", lineCount, err.Error())
+		}
+		if err := emit(item); err != nil {
+			return lineCount, err
+		}
+	}
+	return lineCount, scanner.Err()
+}
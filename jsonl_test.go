@@ -0,0 +1,44 @@
+package logparser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLUnknownCost(t *testing.T) {
+	s := NewTMState()
+	base := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	commits := []Item{}
+	for h := 1; h <= 3; h++ {
+		commits = append(commits, s.commit(base.Add(time.Duration(h)*time.Second), h, 0, "AB"))
+	}
+
+	var buf bytes.Buffer
+	sink := NewJSONLSink(&buf)
+	for _, item := range commits {
+		if err := sink.Emit(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	first := strings.SplitN(buf.String(), "\n", 2)[0]
+	if !strings.Contains(first, `"block_cost":null`) {
+		t.Fatalf("first commit exported as %s, want a null block cost", first)
+	}
+
+	direct, read := NewStats(), NewStats()
+	for _, item := range commits {
+		direct.Add(item)
+	}
+	if _, err := ReadJSONL(&buf, read.Emit); err != nil {
+		t.Fatal(err)
+	}
+	key := StatKey{Metric: "tmCommit.block_cost"}
+	if got, want := read.Sketch(key).Count(), direct.Sketch(key).Count(); got != want || got != 2 {
+		t.Fatalf("block costs read back %d, counted from the items %d, want 2", got, want)
+	}
+}
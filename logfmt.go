@@ -0,0 +1,96 @@
+package logparser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ------------- logfmt -------------- //
+
+// logfmtPair as a `KEY=VALUE` pair of a logfmt text, a bare key has an empty
+// value
+type logfmtPair struct {
+	key   string
+	value string
+}
+
+func isLogfmtSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// parseLogfmt tokenizes a text like `a=1 b="x \"y\"" c`, a value in double
+// quotes may hold spaces and the escapes of a Go string
+func parseLogfmt(text string) ([]logfmtPair, error) {
+	ret := []logfmtPair{}
+	n := len(text)
+	for i := 0; i < n; {
+		if isLogfmtSpace(text[i]) {
+			i++
+			continue
+		}
+
+		start := i
+		for i < n && text[i] != '=' && !isLogfmtSpace(text[i]) {
+			i++
+		}
+		key := text[start:i]
+		if len(key) == 0 {
+			return nil, fmt.Errorf("empty key at %d: %s", start, text)
+		}
+		if i == n || text[i] != '=' {
+			ret = append(ret, logfmtPair{key: key})
+			continue
+		}
+		i++
+
+		if i < n && text[i] == '"' {
+			end := i + 1
+			for end < n && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= n {
+				return nil, fmt.Errorf("unterminated value of %s: %s", key, text)
+			}
+			value, err := strconv.Unquote(text[i : end+1])
+			if err != nil {
+				// not a Go string, only unescape the quotes
+				value = strings.ReplaceAll(text[i+1:end], `\"`, `"`)
+			}
+			ret = append(ret, logfmtPair{key: key, value: value})
+			i = end + 1
+			continue
+		}
+
+		start = i
+		for i < n && !isLogfmtSpace(text[i]) {
+			i++
+		}
+		ret = append(ret, logfmtPair{key: key, value: text[start:i]})
+	}
+	return ret, nil
+}
+
+// formatLogfmt formats the pairs sorted by key, quoting the values which
+// would not read back otherwise
+func formatLogfmt(pairs map[string]string) string {
+	keys := make([]string, 0, len(pairs))
+	for key := range pairs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := pairs[key]
+		if len(value) == 0 || strings.ContainsAny(value, " \t\"=\\") {
+			value = strconv.Quote(value)
+		}
+		parts = append(parts, key+"="+value)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,162 @@
+package logparser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want []logfmtPair
+		err  bool
+	}{
+		{"empty", "", []logfmtPair{}, false},
+		{"pairs", "a=1 b=two", []logfmtPair{{"a", "1"}, {"b", "two"}}, false},
+		{"extra spaces", "  a=1 \t b=2  ", []logfmtPair{{"a", "1"}, {"b", "2"}}, false},
+		{"bare key", "state height=1", []logfmtPair{{"state", ""}, {"height", "1"}}, false},
+		{"empty value", "a= b=2", []logfmtPair{{"a", ""}, {"b", "2"}}, false},
+		{"quoted", `name="bank module" cost=1ms`, []logfmtPair{{"name", "bank module"}, {"cost", "1ms"}}, false},
+		{"escaped quote", `memo="say \"hi\"" x=1`, []logfmtPair{{"memo", `say "hi"`}, {"x", "1"}}, false},
+		{"escaped backslash", `p="a\\b"`, []logfmtPair{{"p", `a\b`}}, false},
+		{"not a go string", `p="a\qb"`, []logfmtPair{{"p", `a\qb`}}, false},
+		{"equals in value", `q="a=b" r=c=d`, []logfmtPair{{"q", "a=b"}, {"r", "c=d"}}, false},
+		{"brackets", "path=[/custom/acc] cost=3ms", []logfmtPair{{"path", "[/custom/acc]"}, {"cost", "3ms"}}, false},
+		{"unterminated", `a="open`, nil, true},
+		{"unterminated escape", `a="open\"`, nil, true},
+		{"empty key", "=1", nil, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseLogfmt(c.text)
+			if c.err {
+				if err == nil {
+					t.Fatalf("no error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFormatLogfmtRoundTrip(t *testing.T) {
+	pairs := map[string]string{
+		"a":    "1",
+		"memo": `say "hi" now`,
+		"p":    `a\b`,
+		"e":    "",
+		"q":    "x=y",
+	}
+	parsed, err := parseTailFields(formatLogfmt(pairs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(map[string]string(parsed), pairs) {
+		t.Fatalf("got %v, want %v", parsed, pairs)
+	}
+}
+
+func TestTailExtra(t *testing.T) {
+	cases := []struct {
+		name  string
+		line  string
+		class string
+		extra map[string]string
+	}{
+		{
+			"any key order",
+			`I[2020-05-12|10:00:01.000] EndBlocker Time module=main cost=12ms name=bank height=2`,
+			"tmEndBlocker", nil,
+		},
+		{
+			"unknown key",
+			`I[2020-05-12|10:00:01.000] Deliver Time module=main height=2 type=send cost=3ms memo="a b"`,
+			"tmHandler", map[string]string{"memo": "a b"},
+		},
+		{
+			"consensus step",
+			`I[2020-05-12|10:00:01.000] enterPrevote(2/0). Current: 2/0/RoundStepPropose module=consensus peer=x`,
+			"tmConsensus", map[string]string{"peer": "x"},
+		},
+		{
+			"consensus timeout",
+			`I[2020-05-12|10:00:01.000] Timed out module=consensus dur=3s height=2 round=0 step=RoundStepPropose note=late`,
+			"tmConsensus", map[string]string{"note": "late"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			item, err := NewTMState().ParseInfo(1, c.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Class() != c.class {
+				t.Fatalf("class %s, want %s", item.Class(), c.class)
+			}
+			e, ok := item.(interface{ Extra() map[string]string })
+			if !ok {
+				t.Fatalf("%T keeps no extra fields", item)
+			}
+			if len(e.Extra()) != len(c.extra) || (len(c.extra) > 0 && !reflect.DeepEqual(e.Extra(), c.extra)) {
+				t.Fatalf("extra %v, want %v", e.Extra(), c.extra)
+			}
+
+			hasField := false
+			for _, f := range item.(Fielder).Fields() {
+				hasField = hasField || f.Name == "extra"
+			}
+			if hasField != (len(c.extra) > 0) {
+				t.Fatalf("extra field exported %v with extras %v", hasField, c.extra)
+			}
+		})
+	}
+}
+
+func TestTailDuration(t *testing.T) {
+	cases := []struct {
+		value string
+		want  time.Duration
+		err   bool
+	}{
+		{"12ms", 12 * time.Millisecond, false},
+		{"1.5s", 1500 * time.Millisecond, false},
+		{"12", 12 * time.Millisecond, false},
+		{"0.5", 500 * time.Microsecond, false},
+		{".5", 500 * time.Microsecond, false},
+		{"inf", 0, true},
+		{"NaN", 0, true},
+		{"+Inf", 0, true},
+		{"1e3", 0, true},
+		{"0x10", 0, true},
+		{"99999999999999999999", 0, true},
+		{"", 0, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			got, err := tmFields{"cost": c.value}.duration("cost")
+			if c.err {
+				if err == nil {
+					t.Fatalf("no error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Fatalf("got %s, want %s", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package logparser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	// ChunksPerWorker as how many chunks a file is split into for each worker,
+	// so that a slow chunk does not hold the others back
+	ChunksPerWorker = 4
+	// MinChunkSize as the least bytes worth a chunk of its own
+	MinChunkSize = 1024 * 1024
+)
+
+type chunk struct {
+	start    int64
+	end      int64
+	lineBase int
+}
+
+type chunkResult struct {
+	lines  int
+	items  []Item
+	errors []*LineError
+	tm     *TMState
+	err    error
+}
+
+// fork returns a parser sharing the classifiers with a fresh format state and
+// no filters, the filters only apply once the chunks are merged
+func (p *Parser) fork() *Parser {
+	tm := NewTMState()
+	tm.SetLogDate(p.tm.LogDate())
+	return &Parser{
+		classifiers:   p.classifiers,
+		registered:    p.registered,
+		filters:       []FilterFunc{},
+		lenient:       p.lenient,
+		maxErrors:     -1,
+		keepMalformed: p.keepMalformed,
+		report:        &ParseReport{},
+		source:        p.source,
+		tm:            tm,
+	}
+}
+
+// splitChunks cuts the file into about n chunks ending on line boundaries
+func splitChunks(file *os.File, size int64, n int) ([]chunk, error) {
+	if max := int(size/MinChunkSize) + 1; n > max {
+		n = max
+	}
+
+	chunks := []chunk{}
+	start := int64(0)
+	for k := 1; k <= n && start < size; k++ {
+		end := size
+		if k < n {
+			end = size * int64(k) / int64(n)
+			if end < start {
+				end = start
+			}
+			// move on to the end of the line
+			r := bufio.NewReader(io.NewSectionReader(file, end, size-end))
+			skipped, err := r.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			end += int64(len(skipped))
+		}
+		if end > start {
+			chunks = append(chunks, chunk{start: start, end: end})
+		}
+		start = end
+	}
+	return chunks, nil
+}
+
+func countLines(r io.Reader) (int, error) {
+	buf := make([]byte, 64*1024)
+	cnt := 0
+	for {
+		n, err := r.Read(buf)
+		cnt += bytes.Count(buf[:n], []byte{'\n'})
+		if err == io.EOF {
+			return cnt, nil
+		}
+		if err != nil {
+			return cnt, err
+		}
+	}
+}
+
+// numberChunks counts the lines of the chunks on the workers to find the
+// number of the first line of each
+func numberChunks(file *os.File, chunks []chunk, workers int) error {
+	counts := make([]int, len(chunks))
+	errs := make([]error, len(chunks))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				c := chunks[idx]
+				counts[idx], errs[idx] = countLines(io.NewSectionReader(file, c.start, c.end-c.start))
+			}
+		}()
+	}
+	for idx := range chunks {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	base := 0
+	for idx := range chunks {
+		if errs[idx] != nil {
+			return errs[idx]
+		}
+		chunks[idx].lineBase = base
+		base += counts[idx]
+	}
+	return nil
+}
+
+func (p *Parser) parseChunk(ctx context.Context, file *os.File, c chunk) *chunkResult {
+	forked := p.fork()
+	ret := &chunkResult{
+		items: []Item{},
+		tm:    forked.tm,
+	}
+
+	ret.lines, ret.err = forked.scan(ctx, io.NewSectionReader(file, c.start, c.end-c.start), c.lineBase, func(item Item) error {
+		ret.items = append(ret.items, item)
+		return nil
+	})
+	ret.errors = forked.report.Errors
+	return ret
+}
+
+// mergeChunk resolves the format state of the chunk items, then emits them
+// through the filters in line order together with the errors of the chunk
+func (p *Parser) mergeChunk(res *chunkResult, emit EmitFunc) error {
+	p.tm.resolveChunk(res.tm, res.items)
+	p.report.Lines += res.lines
+
+	errs := res.errors
+	for _, item := range res.items {
+		if src, ok := item.(Sourced); ok {
+			for len(errs) > 0 && errs[0].Line <= src.Line() {
+				if err := p.recordError(errs[0]); err != nil {
+					return err
+				}
+				errs = errs[1:]
+			}
+		}
+
+		if _, ok := item.(*MalformedItem); ok {
+			p.report.Items++
+			if err := emit(item); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.emitFiltered(item, emit); err != nil {
+			return err
+		}
+	}
+	for _, e := range errs {
+		if err := p.recordError(e); err != nil {
+			return err
+		}
+	}
+	return res.err
+}
+
+func (p *Parser) parseParallel(ctx context.Context, path string, workers int, emit EmitFunc) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	magic := make([]byte, 4)
+	n, err := file.ReadAt(magic, 0)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if workers < 2 || isCompressed(magic[:n]) {
+		// a compressed stream can not be split, parse it in one go
+		p.source = path
+		src, err := OpenLog(path)
+		if err != nil {
+			return 0, err
+		}
+		defer src.Close()
+		return p.scan(ctx, src, 0, emit)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	chunks, err := splitChunks(file, info.Size(), workers*ChunksPerWorker)
+	if err != nil {
+		return 0, err
+	}
+	if err := numberChunks(file, chunks, workers); err != nil {
+		return 0, err
+	}
+
+	p.source = path
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		// the workers are done with the file before it is closed
+		cancel()
+		wg.Wait()
+	}()
+
+	// the chunks are parsed on a pool of workers, at most 2*workers chunks
+	// are parsed or waiting to be merged at a time: the slot of a chunk is
+	// released once it is merged
+	slots := make(chan struct{}, 2*workers)
+	jobs := make(chan int)
+	results := make([]chan *chunkResult, len(chunks))
+	for idx := range results {
+		results[idx] = make(chan *chunkResult, 1)
+	}
+	go func() {
+		defer close(jobs)
+		for idx := range chunks {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- idx:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] <- p.parseChunk(ctx, file, chunks[idx])
+			}
+		}()
+	}
+
+	total := 0
+	for idx := range chunks {
+		var res *chunkResult
+		select {
+		case res = <-results[idx]:
+		case <-ctx.Done():
+			return total, ctx.Err()
+		}
+		<-slots
+
+		total += res.lines
+		if err := p.mergeChunk(res, emit); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ParseParallel parses the file on a pool of workers: it is split into chunks
+// on line boundaries which are parsed concurrently, then merged so that the
+// items are emitted in the original line order with the format state
+// reconciled across the chunks. Compressed files are parsed sequentially
+func (p *Parser) ParseParallel(ctx context.Context, path string, workers int, emit EmitFunc) (int, error) {
+	p.report = &ParseReport{}
+	defer func() {
+		p.source = ""
+	}()
+	return p.parseParallel(ctx, path, workers, emit)
+}
+
+func ParseParallel(ctx context.Context, path string, workers int, emit EmitFunc) (int, error) {
+	return defaultParser.ParseParallel(ctx, path, workers, emit)
+}
+
+// ParseFilesParallel parses the independent files one after another, each of
+// them with ParseParallel, as one run like ParseFiles does
+func (p *Parser) ParseFilesParallel(ctx context.Context, paths []string, workers int, emit EmitFunc) (int, error) {
+	return p.ParseFileSetsParallel(ctx, singleSets(paths), workers, emit)
+}
+
+// ParseFileSetsParallel parses the sets of files like ParseFileSets does, each
+// file with ParseParallel
+func (p *Parser) ParseFileSetsParallel(ctx context.Context, sets [][]string, workers int, emit EmitFunc) (int, error) {
+	p.report = &ParseReport{}
+	defer func() {
+		p.source = ""
+	}()
+
+	total := 0
+	for idx, set := range sets {
+		if idx > 0 {
+			p.tm.reset()
+		}
+		for _, path := range set {
+			cnt, err := p.parseParallel(ctx, path, workers, emit)
+			total += cnt
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,179 @@
+package logparser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testHeights makes the logs span several chunks of MinChunkSize
+const testHeights = 30000
+
+// writeLog writes the lines of every height from the start, the blocks taking
+// 1 to 1.6s, returns the path
+func writeLog(t *testing.T, name string, start time.Time, lines func(h int, at, end time.Time) []string) string {
+	var b strings.Builder
+	at := start
+	for h := 1; h <= testHeights; h++ {
+		end := at.Add(time.Second + time.Duration(h%7)*100*time.Millisecond)
+		for _, line := range lines(h, at, end) {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		at = end
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func tmLines(h int, at, end time.Time) []string {
+	ret := []string{
+		fmt.Sprintf("I[%s] EndBlocker Time module=main height=%d name=bank cost=%dms", at.Format(TMStampFmt), h, h%30+1),
+		fmt.Sprintf("I[%s] Query Time module=main path=[/custom/acc] cost=%dms", at.Format(TMStampFmt), h%5+1),
+		fmt.Sprintf("I[%s] Executed block module=state height=%d validTxs=1 invalidTxs=0", at.Format(TMStampFmt), h),
+		fmt.Sprintf("I[%s] Committed state module=state height=%d txs=1 appHash=AB%d", end.Format(TMStampFmt), h, h),
+	}
+	if h%1000 == 0 {
+		ret = append(ret,
+			fmt.Sprintf("E[%s] Stopping peer for error module=p2p err=EOF", end.Format(TMStampFmt)),
+			"not a tendermint line",
+		)
+	}
+	return ret
+}
+
+func cometLines(h int, at, end time.Time) []string {
+	const layout = "15:04:05.000"
+	return []string{
+		fmt.Sprintf("%s INF entering new round current=%d/0/RoundStepNewHeight height=%d module=consensus round=0", at.Format(layout), h, h),
+		fmt.Sprintf("%s INF EndBlocker Time height=%d module=main name=bank cost=%dms", at.Format(layout), h, h%30+1),
+		fmt.Sprintf("%s INF executed block height=%d module=state num_invalid_txs=0 num_valid_txs=1", at.Format(layout), h),
+		fmt.Sprintf("%s INF committed state app_hash=AB%d height=%d module=state num_txs=1", end.Format(layout), h, h),
+	}
+}
+
+func newTestParser(logDate time.Time) *Parser {
+	p := NewParser()
+	p.TM().SetLogDate(logDate)
+	for _, register := range []func() error{p.RegisterTMPrefix, p.RegisterTMComet, p.RegisterTMJSON} {
+		if err := register(); err != nil {
+			panic(err)
+		}
+	}
+	return p
+}
+
+// dump renders every item with its source, stamp and fields, so that any
+// difference of the parses shows
+func dump(items []Item) []string {
+	ret := make([]string, 0, len(items))
+	for _, item := range items {
+		line := fmt.Sprintf("%s %s %v", item.Class(), item.Stamp().Format(time.RFC3339Nano), item.Format())
+		if src, ok := item.(Sourced); ok {
+			line = fmt.Sprintf("%s:%d %s", src.Source(), src.Line(), line)
+		}
+		if fielder, ok := item.(Fielder); ok {
+			for _, f := range fielder.Fields() {
+				line += " " + f.Name + "=" + f.String()
+			}
+		}
+		ret = append(ret, line)
+	}
+	return ret
+}
+
+func parseBoth(t *testing.T, logDate time.Time, sets [][]string) ([]Item, []Item) {
+	var seq, par []Item
+	if _, err := newTestParser(logDate).ParseFileSets(context.Background(), sets, func(i Item) error {
+		seq = append(seq, i)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTestParser(logDate).ParseFileSetsParallel(context.Background(), sets, 4, func(i Item) error {
+		par = append(par, i)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return seq, par
+}
+
+func TestParallelMatchesSequential(t *testing.T) {
+	tmStart := time.Date(2020, 5, 12, 10, 0, 0, 0, time.UTC)
+	// the comet logs cross midnight about a third in
+	cometStart := time.Date(2020, 3, 1, 20, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		logDate time.Time
+		sets    func(t *testing.T) [][]string
+	}{
+		{"tendermint", time.Time{}, func(t *testing.T) [][]string {
+			return [][]string{{writeLog(t, "node.log", tmStart, tmLines)}}
+		}},
+		{"cometbft past midnight", time.Time{}, func(t *testing.T) [][]string {
+			return [][]string{{writeLog(t, "node-2020-03-01.log", cometStart, cometLines)}}
+		}},
+		{"cometbft rotated with log date", cometStart, func(t *testing.T) [][]string {
+			path := writeLog(t, "node.log", cometStart, cometLines)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.SplitAfter(string(data), "\n")
+			half := len(lines) / 2
+			older, newer := path+".1", path
+			if err := os.WriteFile(older, []byte(strings.Join(lines[:half], "")), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(newer, []byte(strings.Join(lines[half:], "")), 0644); err != nil {
+				t.Fatal(err)
+			}
+			return [][]string{{older, newer}}
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			seq, par := parseBoth(t, c.logDate, c.sets(t))
+			if len(seq) == 0 {
+				t.Fatal("nothing parsed")
+			}
+			want, got := dump(seq), dump(par)
+			if !reflect.DeepEqual(want, got) {
+				for idx := range want {
+					if idx >= len(got) || want[idx] != got[idx] {
+						t.Fatalf("item %d differs:\nsequential %s\nparallel   %v", idx, want[idx], got[idx:idx+1])
+					}
+				}
+				t.Fatalf("parallel parsed %d items, sequential %d", len(got), len(want))
+			}
+
+			// every commit after the first counts from the one before it
+			var prev *TMInfoCommit
+			for _, item := range seq {
+				commit, ok := item.(*TMInfoCommit)
+				if !ok {
+					continue
+				}
+				if prev == nil && commit.CostKnown() {
+					t.Fatalf("first commit at %d has a known cost %s", commit.height, commit.cost)
+				}
+				if prev != nil && (!commit.CostKnown() || commit.cost <= 0 || commit.cost != commit.stamp.Sub(prev.stamp)) {
+					t.Fatalf("commit at %d costs %s after %s", commit.height, commit.cost, prev.stamp)
+				}
+				prev = commit
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+// Package parquet writes the parsed items into Apache Parquet files, one file
+// per item class with the columns typed by the item fields
+package parquet
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	pq "github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"github.com/tjan147/logparser"
+)
+
+const (
+	// DefaultRowGroupSize as the default bytes of a row group
+	DefaultRowGroupSize = 128 * 1024 * 1024
+
+	// parallel marshalling of the writer
+	writerNP = 4
+)
+
+// Options tunes the written files
+type Options struct {
+	// RowGroupSize as the bytes buffered into a row group
+	RowGroupSize int64
+	// Compression as one of none, snappy, gzip and zstd
+	Compression string
+}
+
+func (o Options) codec() (pq.CompressionCodec, error) {
+	switch strings.ToLower(o.Compression) {
+	case "", "snappy":
+		return pq.CompressionCodec_SNAPPY, nil
+	case "none":
+		return pq.CompressionCodec_UNCOMPRESSED, nil
+	case "gzip":
+		return pq.CompressionCodec_GZIP, nil
+	case "zstd":
+		return pq.CompressionCodec_ZSTD, nil
+	default:
+	}
+	return 0, fmt.Errorf("unknown parquet compression %s", o.Compression)
+}
+
+// columnTag declares a column: ints and durations in milliseconds as int64,
+// floats as double, times as millisecond timestamps and strings dictionary
+// encoded. The durations are optional, a cost not known is null
+func columnTag(name string, kind logparser.FieldKind) string {
+	switch kind {
+	case logparser.FieldInt:
+		return fmt.Sprintf("name=%s, type=INT64", name)
+	case logparser.FieldDuration:
+		return fmt.Sprintf("name=%s, type=INT64, repetitiontype=OPTIONAL", name)
+	case logparser.FieldTime:
+		return fmt.Sprintf("name=%s, type=INT64, convertedtype=TIMESTAMP_MILLIS", name)
+	case logparser.FieldFloat:
+		return fmt.Sprintf("name=%s, type=DOUBLE", name)
+	default:
+	}
+	return fmt.Sprintf("name=%s, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY", name)
+}
+
+// schemaOf prepends the source columns to the columns of the class
+func schemaOf(sample logparser.Item) []string {
+	ret := []string{
+		columnTag("source_file", logparser.FieldString),
+		columnTag("source_line", logparser.FieldInt),
+	}
+	for _, col := range logparser.ColumnsOf(sample) {
+		ret = append(ret, columnTag(col.Name, col.Kind))
+	}
+	return ret
+}
+
+func value(f logparser.Field) interface{} {
+	switch v := f.Value.(type) {
+	case nil:
+		return nil
+	case int:
+		return int64(v)
+	case time.Duration:
+		return v.Milliseconds()
+	case time.Time:
+		return v.UnixNano() / int64(time.Millisecond)
+	case float64:
+		return v
+	default:
+	}
+	return f.String()
+}
+
+func rowOf(item logparser.Item) []interface{} {
+	row := []interface{}{"", int64(0)}
+	if src, ok := item.(logparser.Sourced); ok {
+		row = []interface{}{src.Source(), int64(src.Line())}
+	}
+	for _, f := range logparser.RowOf(item) {
+		row = append(row, value(f))
+	}
+	return row
+}
+
+type classFile struct {
+	file *os.File
+	w    *writer.CSVWriter
+}
+
+// Sink streams items into one parquet file per class, the files are named
+// like `PREFIX.CLASS.parquet` under the dir
+type Sink struct {
+	dir    string
+	prefix string
+	opts   Options
+
+	files map[string]*classFile
+}
+
+func NewSink(dir, prefix string, opts Options) *Sink {
+	return &Sink{
+		dir:    dir,
+		prefix: prefix,
+		opts:   opts,
+		files:  map[string]*classFile{},
+	}
+}
+
+// PathOf returns the output path of the class
+func (s *Sink) PathOf(class string) string {
+	return path.Join(s.dir, s.prefix+"."+class+".parquet")
+}
+
+func createClassFile(path string, sample logparser.Item, opts Options) (*classFile, error) {
+	codec, err := opts.codec()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := writer.NewCSVWriterFromWriter(schemaOf(sample), file, writerNP)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error create parquet writer of %s: %s", sample.Class(), err.Error())
+	}
+	w.CompressionType = codec
+	w.RowGroupSize = DefaultRowGroupSize
+	if opts.RowGroupSize > 0 {
+		w.RowGroupSize = opts.RowGroupSize
+	}
+
+	return &classFile{file: file, w: w}, nil
+}
+
+// Emit as the EmitFunc writing the item into the file of its class
+func (s *Sink) Emit(item logparser.Item) error {
+	cf, ok := s.files[item.Class()]
+	if !ok {
+		var err error
+		if cf, err = createClassFile(s.PathOf(item.Class()), item, s.opts); err != nil {
+			return err
+		}
+		s.files[item.Class()] = cf
+	}
+	return cf.w.Write(rowOf(item))
+}
+
+// Flush does nothing, a parquet file is only readable once closed
+func (s *Sink) Flush() error {
+	return nil
+}
+
+// Classes returns the classes written so far
+func (s *Sink) Classes() []string {
+	ret := make([]string, 0, len(s.files))
+	for class := range s.files {
+		ret = append(ret, class)
+	}
+	return ret
+}
+
+// Close writes the footers and closes all the opened files
+func (s *Sink) Close() error {
+	var ret error
+	for _, cf := range s.files {
+		if err := cf.w.WriteStop(); err != nil && ret == nil {
+			ret = err
+		}
+		if err := cf.file.Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+// SaveAsParquet writes the items of one class into the file at path
+func SaveAsParquet(path string, content []logparser.Item, opts Options) error {
+	if len(content) == 0 {
+		return fmt.Errorf("nothing to save into %s", path)
+	}
+
+	cf, err := createClassFile(path, content[0], opts)
+	if err != nil {
+		return err
+	}
+	for _, item := range content {
+		if err := cf.w.Write(rowOf(item)); err != nil {
+			cf.file.Close()
+			return err
+		}
+	}
+	if err := cf.w.WriteStop(); err != nil {
+		cf.file.Close()
+		return err
+	}
+	return cf.file.Close()
+}
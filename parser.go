@@ -0,0 +1,202 @@
+package logparser
+
+import (
+	"bufio"
+	"context"
+	"io"
+)
+
+// --------------- parser ---------------- //
+
+type ParseFunc = func(int, string) (Item, error)
+
+type FilterFunc = func(Item) bool
+
+// Parser owns the classifier registry, the filter chain and the per format
+// state, so that several parses can run in one process without interfering
+type Parser struct {
+	classifiers []*classifier
+	registered  int
+	filters     []FilterFunc
+
+	lenient       bool
+	maxErrors     int
+	keepMalformed bool
+	report        *ParseReport
+
+	// source as the file being parsed
+	source string
+
+	tm *TMState
+}
+
+func NewParser() *Parser {
+	return &Parser{
+		classifiers: []*classifier{},
+		filters:     []FilterFunc{},
+		report:      &ParseReport{},
+		tm:          NewTMState(),
+	}
+}
+
+// defaultParser backs the package level functions
+var defaultParser = NewParser()
+
+// DefaultParser returns the instance used by the package level functions
+func DefaultParser() *Parser {
+	return defaultParser
+}
+
+// TM returns the tendermint height tracker of the parser
+func (p *Parser) TM() *TMState {
+	return p.tm
+}
+
+// -------------- filter --------------- //
+
+func (p *Parser) RegisterItemFilter(filter FilterFunc) {
+	p.filters = append(p.filters, filter)
+}
+
+func (p *Parser) GetItemFiltersCount() int {
+	return len(p.filters)
+}
+
+func RegisterItemFilter(filter FilterFunc) {
+	defaultParser.RegisterItemFilter(filter)
+}
+
+func GetItemFiltersCount() int {
+	return defaultParser.GetItemFiltersCount()
+}
+
+func (p *Parser) passFilters(item Item) bool {
+	for _, f := range p.filters {
+		if !f(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// -------------- log parsing ---------------- //
+
+// MaxLineSize as the longest line the scanner accepts
+const MaxLineSize = 1024 * 1024
+
+// EmitFunc receives every parsed item which passes the filters, returning an
+// error stops the parsing
+type EmitFunc = func(Item) error
+
+// ParseLine classifies one line and parses it with the matching parser
+func (p *Parser) ParseLine(lineNum int, lineText string) (Item, error) {
+	if c, groups := p.classify(lineText); c != nil {
+		return c.parse(p, lineNum, lineText, groups)
+	}
+	return NewUnknownItem(lineText), nil
+}
+
+func ParseLine(lineNum int, lineText string) (Item, error) {
+	return defaultParser.ParseLine(lineNum, lineText)
+}
+
+// handleLine parses one line into the report and emits the item
+func (p *Parser) handleLine(lineNum int, lineText string, emit EmitFunc) error {
+	p.report.Lines++
+
+	c, groups := p.classify(lineText)
+	if c == nil {
+		return p.emitFiltered(p.attribute(NewUnknownItem(lineText), lineNum), emit)
+	}
+
+	logItem, err := c.parse(p, lineNum, lineText, groups)
+	if err == nil {
+		return p.emitFiltered(p.attribute(logItem, lineNum), emit)
+	}
+
+	lineErr := &LineError{
+		Source:     p.source,
+		Line:       lineNum,
+		Classifier: c.info,
+		Raw:        lineText,
+		Err:        err,
+	}
+	if err := p.recordError(lineErr); err != nil {
+		return err
+	}
+	if p.keepMalformed {
+		p.report.Items++
+		return emit(p.attribute(NewMalformedItem(lineErr), lineNum))
+	}
+	return nil
+}
+
+func (p *Parser) emitFiltered(item Item, emit EmitFunc) error {
+	if !p.passFilters(item) {
+		return nil
+	}
+	p.report.Items++
+	return emit(item)
+}
+
+// ParseReader parses r line by line and hands each item to emit as soon as it
+// is parsed, returns the number of lines read. The details are kept in the
+// Report() until the next parse
+func (p *Parser) ParseReader(ctx context.Context, r io.Reader, emit EmitFunc) (int, error) {
+	p.report = &ParseReport{}
+	p.source = ""
+	return p.scan(ctx, r, 0, emit)
+}
+
+// scan parses the lines of r numbering them after lineBase
+func (p *Parser) scan(ctx context.Context, r io.Reader, lineBase int, emit EmitFunc) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxLineSize)
+	lineCount := 0
+
+	for scanner.Scan() {
+		select {
+		case <-ctx.Done():
+			return lineCount, ctx.Err()
+		default:
+		}
+
+		lineCount++
+		if err := p.handleLine(lineBase+lineCount, scanner.Text(), emit); err != nil {
+			return lineCount, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return lineCount, err
+	}
+
+	return lineCount, nil
+}
+
+func ParseReader(ctx context.Context, r io.Reader, emit EmitFunc) (int, error) {
+	return defaultParser.ParseReader(ctx, r, emit)
+}
+
+type ParseResult = map[string][]Item
+
+// Collect returns an EmitFunc gathering the items into res by class
+func Collect(res ParseResult) EmitFunc {
+	return func(i Item) error {
+		res[i.Class()] = append(res[i.Class()], i)
+		return nil
+	}
+}
+
+func (p *Parser) ParseByLine(path string) (ParseResult, int, error) {
+	parsedLogs := ParseResult{}
+	lineCount, err := p.ParseFiles(context.Background(), []string{path}, Collect(parsedLogs))
+	if err != nil {
+		return nil, lineCount, err
+	}
+
+	return parsedLogs, lineCount, nil
+}
+
+func ParseByLine(path string) (ParseResult, int, error) {
+	return defaultParser.ParseByLine(path)
+}
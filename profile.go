@@ -0,0 +1,143 @@
+package logparser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ------------- block profile -------------- //
+
+// ClassBlockProfile as the Item.Class() of the per height aggregation
+const ClassBlockProfile = "blockProfile"
+
+var _ Item = (*BlockProfile)(nil)
+
+// BlockProfile as the costs spent on one height, the endblocker, handler and
+// querier costs are summed over the items of the height
+type BlockProfile struct {
+	height    int
+	stamp     time.Time
+	txNum     int
+	blockCost time.Duration
+	// blockKnown as whether the block cost of the height is known
+	blockKnown     bool
+	endBlockerCost time.Duration
+	handlerCost    time.Duration
+	querierCost    time.Duration
+	errCount       int
+}
+
+func (i *BlockProfile) Data() string {
+	return fmt.Sprintf("height=%d txs=%d block=%s endblocker=%s handler=%s querier=%s errs=%d",
+		i.height, i.txNum, i.blockCost, i.endBlockerCost, i.handlerCost, i.querierCost, i.errCount)
+}
+
+func (i BlockProfile) Header() []string {
+	return []string{"height", "stamp", "txs", "block_cost", "endblocker_cost", "handler_cost", "querier_cost", "err_count"}
+}
+
+func (i *BlockProfile) Format() []string {
+	stamp := ""
+	if !i.stamp.IsZero() {
+		stamp = i.stamp.Format(time.RFC3339)
+	}
+	blockCost := ""
+	if i.blockKnown {
+		blockCost = strconv.FormatInt(i.blockCost.Milliseconds(), 10)
+	}
+	return []string{
+		strconv.Itoa(i.height),
+		stamp,
+		strconv.Itoa(i.txNum),
+		blockCost,
+		strconv.FormatInt(i.endBlockerCost.Milliseconds(), 10),
+		strconv.FormatInt(i.handlerCost.Milliseconds(), 10),
+		strconv.FormatInt(i.querierCost.Milliseconds(), 10),
+		strconv.Itoa(i.errCount),
+	}
+}
+
+func (i *BlockProfile) Fields() []Field {
+	blockCost := NullField("block_cost", FieldDuration)
+	if i.blockKnown {
+		blockCost = DurationField("block_cost", i.blockCost)
+	}
+	return []Field{
+		IntField("height", i.height),
+		TimeField("stamp", i.stamp),
+		IntField("txs", i.txNum),
+		blockCost,
+		DurationField("endblocker_cost", i.endBlockerCost),
+		DurationField("handler_cost", i.handlerCost),
+		DurationField("querier_cost", i.querierCost),
+		IntField("err_count", i.errCount),
+	}
+}
+
+func (i *BlockProfile) Stamp() time.Time {
+	return i.stamp
+}
+
+func (i BlockProfile) Class() string {
+	return ClassBlockProfile
+}
+
+func (i BlockProfile) Level() ItemLevel {
+	return LevelInfo
+}
+
+// BuildBlockProfile joins the commit of every height with the summed
+// endblocker, handler and querier costs and the count of errors logged on it,
+// returns one BlockProfile per height in height order. A height without a
+// commit, or with the first commit of the run whose cost is not known, is
+// still reported with the block cost empty, the items before the first commit
+// are left out
+func BuildBlockProfile(res ParseResult) []Item {
+	profiles := map[int]*BlockProfile{}
+	at := func(h int) *BlockProfile {
+		p, ok := profiles[h]
+		if !ok {
+			p = &BlockProfile{height: h}
+			profiles[h] = p
+		}
+		return p
+	}
+
+	for _, items := range res {
+		for _, item := range items {
+			switch i := item.(type) {
+			case *TMInfoCommit:
+				p := at(i.height)
+				p.stamp = i.stamp
+				p.txNum = i.txNum
+				p.blockCost = i.cost
+				p.blockKnown = i.costKnown
+			case *TMInfoEndBlocker:
+				at(i.height).endBlockerCost += i.cost
+			case *TMInfoHandler:
+				at(i.height).handlerCost += i.cost
+			case *TMInfoQuerier:
+				at(i.height).querierCost += i.cost
+			case *TMItemErr:
+				at(i.height).errCount++
+			}
+		}
+	}
+
+	// the items logged before the first commit of a run carry no height
+	delete(profiles, 0)
+
+	heights := make([]int, 0, len(profiles))
+	for h := range profiles {
+		heights = append(heights, h)
+	}
+	sort.Ints(heights)
+
+	ret := make([]Item, 0, len(heights))
+	for _, h := range heights {
+		ret = append(ret, profiles[h])
+	}
+	return ret
+}
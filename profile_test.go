@@ -0,0 +1,32 @@
+package logparser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildBlockProfileSkipsNoHeight(t *testing.T) {
+	s := NewTMState()
+	base := time.Date(2020, 5, 12, 10, 0, 0, 0, time.UTC)
+	res := ParseResult{}
+	emit := Collect(res)
+
+	// logged before the first commit, at no height
+	emit(NewTMInfoQuerier(base, s.Height(), "/custom/acc", time.Millisecond))
+	emit(NewTMItemErr(base, 1, s.Height(), "Stopping peer for error", "p2p err=EOF"))
+	for h := 1; h <= 2; h++ {
+		emit(s.commit(base.Add(time.Duration(h)*time.Second), h, 0, "AB"))
+		emit(NewTMInfoQuerier(base.Add(time.Duration(h)*time.Second), s.Height(), "/custom/acc", time.Millisecond))
+	}
+
+	profiles := BuildBlockProfile(res)
+	if len(profiles) != 2 {
+		t.Fatalf("%d profiles, want the 2 committed heights", len(profiles))
+	}
+	for idx, item := range profiles {
+		p := item.(*BlockProfile)
+		if p.height != idx+1 || p.querierCost != time.Millisecond || p.errCount != 0 {
+			t.Fatalf("profile %d: %s", idx, p.Data())
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package logparser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrTooManyErrors as the error stopping a lenient parse once more lines than
+// allowed failed to parse
+var ErrTooManyErrors = errors.New("too many malformed lines")
+
+// LineError records a line the classifier claimed but failed to parse
+type LineError struct {
+	Source     string
+	Line       int
+	Classifier ClassifierInfo
+	Raw        string
+	Err        error
+}
+
+func (e *LineError) Error() string {
+	if len(e.Source) > 0 {
+		return fmt.Sprintf("%s:%d by %s: %s", e.Source, e.Line, e.Classifier, e.Err.Error())
+	}
+	return fmt.Sprintf("line %d by %s: %s", e.Line, e.Classifier, e.Err.Error())
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// ParseReport summarizes one parse
+type ParseReport struct {
+	Lines  int
+	Items  int
+	Errors []*LineError
+}
+
+// --------------- malformed item def ----------------- //
+
+// ClassMalformed as the Item.Class() of the lines failed to parse
+const ClassMalformed = "malformed"
+
+var _ Item = (*MalformedItem)(nil)
+
+// MalformedItem holds a line failed to parse in the lenient mode
+type MalformedItem struct {
+	itemSource
+
+	line       int
+	classifier string
+	data       string
+	err        string
+}
+
+func NewMalformedItem(e *LineError) Item {
+	return &MalformedItem{
+		line:       e.Line,
+		classifier: e.Classifier.Pattern,
+		data:       e.Raw,
+		err:        e.Err.Error(),
+	}
+}
+
+func (i *MalformedItem) Data() string {
+	return i.data
+}
+
+func (i MalformedItem) Header() []string {
+	return []string{"line", "classifier", "data", "error"}
+}
+
+func (i *MalformedItem) Format() []string {
+	return []string{strconv.Itoa(i.line), i.classifier, i.data, i.err}
+}
+
+func (i *MalformedItem) Fields() []Field {
+	return []Field{
+		IntField("line", i.line),
+		StringField("classifier", i.classifier),
+		StringField("data", i.data),
+		StringField("error", i.err),
+	}
+}
+
+func (i *MalformedItem) Stamp() time.Time {
+	return time.Time{}
+}
+
+func (i MalformedItem) Class() string {
+	return ClassMalformed
+}
+
+func (i MalformedItem) Level() ItemLevel {
+	return LevelErr
+}
+
+// --------------- lenient mode ----------------- //
+
+// SetLenient switches the parser into the error tolerant mode: a line failing
+// to parse is recorded in the report instead of stopping the parse, until more
+// than maxErrors lines failed, a negative maxErrors means no limit
+func (p *Parser) SetLenient(maxErrors int) {
+	p.lenient = true
+	p.maxErrors = maxErrors
+}
+
+// SetKeepMalformed makes the lenient mode emit the failed lines as
+// MalformedItem, they bypass the filters since they carry no data to filter by
+func (p *Parser) SetKeepMalformed(keep bool) {
+	p.keepMalformed = keep
+}
+
+// Report returns the report of the latest parse
+func (p *Parser) Report() *ParseReport {
+	return p.report
+}
+
+func (p *Parser) recordError(e *LineError) error {
+	p.report.Errors = append(p.report.Errors, e)
+	if !p.lenient {
+		return e.Err
+	}
+	if p.maxErrors >= 0 && len(p.report.Errors) > p.maxErrors {
+		return fmt.Errorf("%w: %d lines failed, the last %s", ErrTooManyErrors, len(p.report.Errors), e.Error())
+	}
+	return nil
+}
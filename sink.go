@@ -0,0 +1,140 @@
+package logparser
+
+import (
+	"encoding/csv"
+	"os"
+	"path"
+	"strconv"
+)
+
+// CSVSink streams items into one csv file per class, the files are named
+// like `PREFIX.CLASS.csv` under the dir
+type CSVSink struct {
+	dir    string
+	prefix string
+	source bool
+	append bool
+
+	files   map[string]*os.File
+	writers map[string]*csv.Writer
+}
+
+func NewCSVSink(dir, prefix string) *CSVSink {
+	return &CSVSink{
+		dir:     dir,
+		prefix:  prefix,
+		files:   map[string]*os.File{},
+		writers: map[string]*csv.Writer{},
+	}
+}
+
+// WithSource prepends the source file and line columns to every row, so the
+// items of several inputs merged into one file can be told apart
+func (s *CSVSink) WithSource() *CSVSink {
+	s.source = true
+	return s
+}
+
+func (s *CSVSink) row(values []string, item Item) []string {
+	if !s.source {
+		return values
+	}
+	file, line := "", ""
+	if src, ok := item.(Sourced); ok {
+		file, line = src.Source(), strconv.Itoa(src.Line())
+	}
+	return append([]string{file, line}, values...)
+}
+
+// WithAppend keeps the existing files and appends the rows to them, the header
+// is only written into an empty file
+func (s *CSVSink) WithAppend() *CSVSink {
+	s.append = true
+	return s
+}
+
+// PathOf returns the output path of the class
+func (s *CSVSink) PathOf(class string) string {
+	return path.Join(s.dir, s.prefix+"."+class+".csv")
+}
+
+func (s *CSVSink) open(item Item) (*csv.Writer, error) {
+	if w, ok := s.writers[item.Class()]; ok {
+		return w, nil
+	}
+
+	mode := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if s.append {
+		mode = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(s.PathOf(item.Class()), mode, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	w := csv.NewWriter(file)
+	if info.Size() == 0 {
+		header := item.Header()
+		if s.source {
+			header = append([]string{"source", "source_line"}, header...)
+		}
+		if err := w.Write(header); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	s.files[item.Class()] = file
+	s.writers[item.Class()] = w
+	return w, nil
+}
+
+// Emit as the EmitFunc writing the item into the file of its class
+func (s *CSVSink) Emit(item Item) error {
+	w, err := s.open(item)
+	if err != nil {
+		return err
+	}
+	return w.Write(s.row(item.Format(), item))
+}
+
+// Classes returns the classes written so far
+func (s *CSVSink) Classes() []string {
+	ret := make([]string, 0, len(s.files))
+	for class := range s.files {
+		ret = append(ret, class)
+	}
+	return ret
+}
+
+// Flush writes the buffered rows out, so the files can be read while the
+// sink is still in use
+func (s *CSVSink) Flush() error {
+	for _, w := range s.writers {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes and closes all the opened files
+func (s *CSVSink) Close() error {
+	var ret error
+	for class, w := range s.writers {
+		w.Flush()
+		if err := w.Error(); err != nil && ret == nil {
+			ret = err
+		}
+		if err := s.files[class].Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
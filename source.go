@@ -0,0 +1,120 @@
+package logparser
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// Sourced is implemented by the items knowing where they were read from,
+// Source() is empty when the input was not a file
+type Sourced interface {
+	Source() string
+	Line() int
+}
+
+type sourceSetter interface {
+	setSource(string, int)
+}
+
+// itemSource as the Sourced implementation embedded into the items
+type itemSource struct {
+	srcFile string
+	srcLine int
+}
+
+func (s *itemSource) Source() string {
+	return s.srcFile
+}
+
+func (s *itemSource) Line() int {
+	return s.srcLine
+}
+
+func (s *itemSource) setSource(file string, line int) {
+	s.srcFile = file
+	s.srcLine = line
+}
+
+// attribute records the current source position on the item
+func (p *Parser) attribute(item Item, lineNum int) Item {
+	if s, ok := item.(sourceSetter); ok {
+		s.setSource(p.source, lineNum)
+	}
+	return item
+}
+
+// ExpandInputs resolves the glob patterns into the input paths, keeping the
+// order of the patterns, a pattern matching nothing is an error
+func ExpandInputs(patterns []string) ([]string, error) {
+	ret := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("error expand %s: %s", pattern, err.Error())
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no input matches %s", pattern)
+		}
+		ret = append(ret, matches...)
+	}
+	return ret, nil
+}
+
+// singleSets puts every path into a set of its own
+func singleSets(paths []string) [][]string {
+	ret := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		ret = append(ret, []string{path})
+	}
+	return ret
+}
+
+// ParseFiles parses the independent files one after another as one run: the
+// line numbers and the format state start over with each file, returns the
+// number of lines read in total
+func (p *Parser) ParseFiles(ctx context.Context, paths []string, emit EmitFunc) (int, error) {
+	return p.ParseFileSets(ctx, singleSets(paths), emit)
+}
+
+func ParseFiles(ctx context.Context, paths []string, emit EmitFunc) (int, error) {
+	return defaultParser.ParseFiles(ctx, paths, emit)
+}
+
+// ParseFileSets parses the sets of files one after another as one run, the
+// files of a set continue each other like the ones listed by RotatedSet: the
+// format state carries over from file to file of a set, while every set
+// starts over keeping only the log date
+func (p *Parser) ParseFileSets(ctx context.Context, sets [][]string, emit EmitFunc) (int, error) {
+	p.report = &ParseReport{}
+	defer func() {
+		p.source = ""
+	}()
+
+	total := 0
+	for idx, set := range sets {
+		if idx > 0 {
+			p.tm.reset()
+		}
+		for _, path := range set {
+			p.source = path
+
+			file, err := OpenLog(path)
+			if err != nil {
+				return total, err
+			}
+			cnt, err := p.scan(ctx, file, 0, emit)
+			file.Close()
+
+			total += cnt
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
+
+func ParseFileSets(ctx context.Context, sets [][]string, emit EmitFunc) (int, error) {
+	return defaultParser.ParseFileSets(ctx, sets, emit)
+}
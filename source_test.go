@@ -0,0 +1,84 @@
+package logparser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseFilesStartOver(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	a := write("a.log", `I[2020-05-12|11:00:00.000] Committed state module=state height=1 txs=0 appHash=A1
+I[2020-05-12|11:00:01.000] Committed state module=state height=2 txs=0 appHash=A2
+`)
+	b := write("b.log", `E[2020-05-12|10:00:00.000] Stopping peer for error module=p2p err=EOF
+I[2020-05-12|10:00:01.000] Committed state module=state height=7 txs=0 appHash=B7
+I[2020-05-12|10:00:02.000] Committed state module=state height=8 txs=0 appHash=B8
+`)
+
+	cases := []struct {
+		name  string
+		parse func(p *Parser, emit EmitFunc) error
+		// height as the height of the error of b.log, set when the state
+		// carries over from a.log
+		height int
+	}{
+		{"sequential", func(p *Parser, emit EmitFunc) error {
+			_, err := p.ParseFiles(context.Background(), []string{a, b}, emit)
+			return err
+		}, 0},
+		{"parallel", func(p *Parser, emit EmitFunc) error {
+			_, err := p.ParseFilesParallel(context.Background(), []string{a, b}, 4, emit)
+			return err
+		}, 0},
+		{"one set", func(p *Parser, emit EmitFunc) error {
+			_, err := p.ParseFileSets(context.Background(), [][]string{{a, b}}, emit)
+			return err
+		}, 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var errs []*TMItemErr
+			commits := map[int]*TMInfoCommit{}
+			err := c.parse(newTestParser(time.Time{}), func(item Item) error {
+				switch i := item.(type) {
+				case *TMItemErr:
+					errs = append(errs, i)
+				case *TMInfoCommit:
+					commits[i.height] = i
+				default:
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(errs) != 1 {
+				t.Fatalf("%d errors parsed, want 1", len(errs))
+			}
+			if errs[0].height != c.height {
+				t.Fatalf("error of b.log at height %d, want %d", errs[0].height, c.height)
+			}
+			known := map[int]bool{1: false, 2: true, 7: c.height > 0, 8: true}
+			for h, want := range known {
+				if commits[h] == nil || commits[h].CostKnown() != want {
+					t.Fatalf("commit at %d: %v, want cost known %v", h, commits[h], want)
+				}
+			}
+			if commits[8].cost != time.Second {
+				t.Fatalf("commit at 8 costs %s, want 1s", commits[8].cost)
+			}
+		})
+	}
+}
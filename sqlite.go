@@ -0,0 +1,185 @@
+package logparser
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TableRunMeta as the table of the run metadata written by SaveAsSQLite
+const TableRunMeta = "run_meta"
+
+// indexed columns of the class tables
+var sqlIndexed = []string{"height", "stamp"}
+
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func sqlType(kind FieldKind) string {
+	switch kind {
+	case FieldInt, FieldDuration:
+		return "INTEGER"
+	case FieldFloat:
+		return "REAL"
+	default:
+	}
+	return "TEXT"
+}
+
+// sqlStampFmt as RFC3339 in UTC with all the nanosecond digits kept, so that
+// the text of the stamps sorts in time order
+const sqlStampFmt = "2006-01-02T15:04:05.000000000Z07:00"
+
+// sqlValue stores durations in milliseconds like the csv does and times as
+// fixed width RFC3339 text which the sqlite date functions understand, a value
+// not known as NULL
+func sqlValue(f Field) interface{} {
+	switch v := f.Value.(type) {
+	case nil:
+		return nil
+	case int:
+		return int64(v)
+	case time.Duration:
+		return v.Milliseconds()
+	case time.Time:
+		return v.UTC().Format(sqlStampFmt)
+	case float64:
+		return v
+	default:
+	}
+	return f.String()
+}
+
+// sqlColumns prepends the source columns to the columns of the class
+func sqlColumns(sample Item) ([]string, []string) {
+	names := []string{"source_file", "source_line"}
+	types := []string{"TEXT", "INTEGER"}
+	for _, col := range ColumnsOf(sample) {
+		names = append(names, col.Name)
+		types = append(types, sqlType(col.Kind))
+	}
+	return names, types
+}
+
+func sqlRow(item Item) []interface{} {
+	row := []interface{}{nil, nil}
+	if src, ok := item.(Sourced); ok {
+		row = []interface{}{src.Source(), src.Line()}
+	}
+	for _, f := range RowOf(item) {
+		row = append(row, sqlValue(f))
+	}
+	return row
+}
+
+func saveClass(tx *sql.Tx, class string, content []Item) error {
+	table := quoteIdent(class)
+	names, types := sqlColumns(content[0])
+
+	defs := make([]string, len(names))
+	quoted := make([]string, len(names))
+	marks := make([]string, len(names))
+	for idx, name := range names {
+		quoted[idx] = quoteIdent(name)
+		defs[idx] = quoted[idx] + " " + types[idx]
+		marks[idx] = "?"
+	}
+
+	if _, err := tx.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(defs, ", "))); err != nil {
+		return fmt.Errorf("error create table %s: %s", class, err.Error())
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(quoted, ", "), strings.Join(marks, ", ")))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, item := range content {
+		if _, err := stmt.Exec(sqlRow(item)...); err != nil {
+			return fmt.Errorf("error insert into %s: %s", class, err.Error())
+		}
+	}
+
+	for _, name := range names {
+		for _, indexed := range sqlIndexed {
+			if name != indexed {
+				continue
+			}
+			index := quoteIdent("idx_" + class + "_" + name)
+			if _, err := tx.Exec(fmt.Sprintf("CREATE INDEX %s ON %s (%s)", index, table, quoteIdent(name))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func saveRunMeta(tx *sql.Tx, res ParseResult, meta map[string]string) error {
+	table := quoteIdent(TableRunMeta)
+	if _, err := tx.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("CREATE TABLE " + table + ` ("key" TEXT PRIMARY KEY, "value" TEXT)`); err != nil {
+		return err
+	}
+
+	all := map[string]string{}
+	for k, v := range meta {
+		all[k] = v
+	}
+	for class, content := range res {
+		all["rows."+class] = strconv.Itoa(len(content))
+	}
+
+	keys := make([]string, 0, len(all))
+	for k := range all {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := tx.Exec("INSERT INTO "+table+` ("key", "value") VALUES (?, ?)`, k, all[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveAsSQLite writes the whole result into the sqlite db in one transaction:
+// a table per Class() with the columns of its Header() typed by the item
+// fields and indexed on height and stamp, plus the run_meta table holding
+// meta together with the row count of every class. Existing tables of the
+// same names are replaced
+func SaveAsSQLite(db *sql.DB, res ParseResult, meta map[string]string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	classes := make([]string, 0, len(res))
+	for class := range res {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		if len(res[class]) == 0 {
+			continue
+		}
+		if err := saveClass(tx, class, res[class]); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := saveRunMeta(tx, res, meta); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,27 @@
+package logparser
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSQLStampOrder(t *testing.T) {
+	base := time.Date(2020, 5, 12, 10, 0, 5, 0, time.UTC)
+	stamps := []time.Time{
+		base,
+		base.Add(500 * time.Millisecond),
+		base.Add(time.Second),
+		base.Add(time.Second + time.Nanosecond),
+		// an offset of its own, the same instant as base plus 2s
+		base.Add(2 * time.Second).In(time.FixedZone("CST", 8*3600)),
+	}
+
+	texts := make([]string, 0, len(stamps))
+	for _, stamp := range stamps {
+		texts = append(texts, sqlValue(TimeField("stamp", stamp)).(string))
+	}
+	if !sort.StringsAreSorted(texts) {
+		t.Fatalf("stamps stored out of time order: %v", texts)
+	}
+}
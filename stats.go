@@ -0,0 +1,411 @@
+package logparser
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ------------- sketch -------------- //
+
+// SketchAccuracy as the relative error of the quantiles of a Sketch
+const SketchAccuracy = 0.01
+
+var (
+	sketchGamma    = (1 + SketchAccuracy) / (1 - SketchAccuracy)
+	sketchLogGamma = math.Log(sketchGamma)
+)
+
+// Sketch as a mergeable histogram of durations, the positive values are
+// counted in logarithmic buckets so that every quantile is within the
+// SketchAccuracy of the exact one, the count, min, max, mean and stddev are
+// exact. Sketches of different files or chunks merge into the sketch of them
+// all
+type Sketch struct {
+	count uint64
+	min   time.Duration
+	max   time.Duration
+	// mean and m2 as the running moments in ns
+	mean float64
+	m2   float64
+
+	zeros   uint64
+	buckets map[int]uint64
+}
+
+func NewSketch() *Sketch {
+	return &Sketch{
+		buckets: map[int]uint64{},
+	}
+}
+
+func sketchBucket(d time.Duration) int {
+	return int(math.Ceil(math.Log(float64(d)) / sketchLogGamma))
+}
+
+func sketchValue(bucket int) float64 {
+	return 2 * math.Pow(sketchGamma, float64(bucket)) / (sketchGamma + 1)
+}
+
+// Add counts a duration in, a negative duration counts as zero
+func (s *Sketch) Add(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	if s.count == 0 || d < s.min {
+		s.min = d
+	}
+	if s.count == 0 || d > s.max {
+		s.max = d
+	}
+
+	s.count++
+	delta := float64(d) - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (float64(d) - s.mean)
+
+	if d == 0 {
+		s.zeros++
+	} else {
+		s.buckets[sketchBucket(d)]++
+	}
+}
+
+// Merge counts all the durations of o in
+func (s *Sketch) Merge(o *Sketch) {
+	if o.count == 0 {
+		return
+	}
+	if s.count == 0 || o.min < s.min {
+		s.min = o.min
+	}
+	if s.count == 0 || o.max > s.max {
+		s.max = o.max
+	}
+
+	n := float64(s.count + o.count)
+	delta := o.mean - s.mean
+	s.m2 += o.m2 + delta*delta*float64(s.count)*float64(o.count)/n
+	s.mean += delta * float64(o.count) / n
+	s.count += o.count
+
+	s.zeros += o.zeros
+	for b, c := range o.buckets {
+		s.buckets[b] += c
+	}
+}
+
+func (s *Sketch) Count() int {
+	return int(s.count)
+}
+
+func (s *Sketch) Min() time.Duration {
+	return s.min
+}
+
+func (s *Sketch) Max() time.Duration {
+	return s.max
+}
+
+func (s *Sketch) Mean() time.Duration {
+	return time.Duration(s.mean)
+}
+
+// StdDev returns the population standard deviation
+func (s *Sketch) StdDev() time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	return time.Duration(math.Sqrt(s.m2 / float64(s.count)))
+}
+
+// Quantile returns the q-quantile, q in [0, 1]
+func (s *Sketch) Quantile(q float64) time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+
+	rank := uint64(q * float64(s.count-1))
+	if rank < s.zeros {
+		return 0
+	}
+	seen := s.zeros
+
+	keys := make([]int, 0, len(s.buckets))
+	for b := range s.buckets {
+		keys = append(keys, b)
+	}
+	sort.Ints(keys)
+
+	for _, b := range keys {
+		seen += s.buckets[b]
+		if seen > rank {
+			v := time.Duration(sketchValue(b))
+			if v < s.min {
+				return s.min
+			}
+			if v > s.max {
+				return s.max
+			}
+			return v
+		}
+	}
+	return s.max
+}
+
+// ------------- stats -------------- //
+
+// StatKey names a statistic as the metric, like `tmEndBlocker.endblocker_cost`,
+// and the group of the items counted, like `module=bank`
+type StatKey struct {
+	Metric string
+	Group  string
+}
+
+// Stats as a Sketch per metric and group of the costs, that is the block cost
+// of the commits, the endblocker cost by module, the handler cost by tx type,
+// the querier cost by path and the bench cost by backend and method
+type Stats struct {
+	sketches map[StatKey]*Sketch
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		sketches: map[StatKey]*Sketch{},
+	}
+}
+
+func (s *Stats) add(metric, group string, d time.Duration) {
+	key := StatKey{Metric: metric, Group: group}
+	sk, ok := s.sketches[key]
+	if !ok {
+		sk = NewSketch()
+		s.sketches[key] = sk
+	}
+	sk.Add(d)
+}
+
+// statSpec picks the duration field of the items counted under the metric
+// and the fields grouping them, an empty class matches every item having the
+// fields
+type statSpec struct {
+	class  string
+	metric string
+	value  string
+	groups []string
+}
+
+// statSpecs as the costs gathered, the first matching spec counts the item
+var statSpecs = []statSpec{
+	{class: "tmCommit", metric: "tmCommit.block_cost", value: "block_cost"},
+	{class: "tmEndBlocker", metric: "tmEndBlocker.endblocker_cost", value: "endblocker_cost", groups: []string{"module"}},
+	{class: "tmHandler", metric: "tmHandler.handler_cost", value: "handler_cost", groups: []string{"type"}},
+	{class: "tmQuerier", metric: "tmQuerier.querier_cost", value: "querier_cost", groups: []string{"path"}},
+	{metric: "bench.cost", value: "cost", groups: []string{"backend", "method"}},
+}
+
+func (spec *statSpec) match(item Item, fields map[string]Field) (time.Duration, string, bool) {
+	if len(spec.class) > 0 && spec.class != item.Class() {
+		return 0, "", false
+	}
+	value, ok := fields[spec.value]
+	if !ok || value.Kind != FieldDuration || value.IsNull() {
+		return 0, "", false
+	}
+
+	groups := make([]string, 0, len(spec.groups))
+	for _, name := range spec.groups {
+		f, ok := fields[name]
+		if !ok {
+			return 0, "", false
+		}
+		groups = append(groups, name+"="+f.String())
+	}
+	return value.Duration(), strings.Join(groups, ","), true
+}
+
+// Add counts the cost of the item in, the items without a cost are ignored
+// and so are the costs not known, like that of the first commit of a run
+func (s *Stats) Add(item Item) {
+	fielder, ok := item.(Fielder)
+	if !ok {
+		return
+	}
+	fields := map[string]Field{}
+	for _, f := range fielder.Fields() {
+		fields[f.Name] = f
+	}
+
+	for idx := range statSpecs {
+		if d, group, ok := statSpecs[idx].match(item, fields); ok {
+			s.add(statSpecs[idx].metric, group, d)
+			return
+		}
+	}
+}
+
+// Emit as the EmitFunc counting the item in
+func (s *Stats) Emit(item Item) error {
+	s.Add(item)
+	return nil
+}
+
+// Merge counts all the items of o in
+func (s *Stats) Merge(o *Stats) {
+	for key, sk := range o.sketches {
+		mine, ok := s.sketches[key]
+		if !ok {
+			mine = NewSketch()
+			s.sketches[key] = mine
+		}
+		mine.Merge(sk)
+	}
+}
+
+// Keys returns the keys of the statistics in metric and group order
+func (s *Stats) Keys() []StatKey {
+	ret := make([]StatKey, 0, len(s.sketches))
+	for key := range s.sketches {
+		ret = append(ret, key)
+	}
+	sort.Slice(ret, func(a, b int) bool {
+		if ret[a].Metric != ret[b].Metric {
+			return ret[a].Metric < ret[b].Metric
+		}
+		return ret[a].Group < ret[b].Group
+	})
+	return ret
+}
+
+// Sketch returns the sketch of the key, nil if nothing was counted
+func (s *Stats) Sketch(key StatKey) *Sketch {
+	return s.sketches[key]
+}
+
+// Rows returns a StatRow per key in the Keys() order
+func (s *Stats) Rows() []Item {
+	keys := s.Keys()
+	ret := make([]Item, 0, len(keys))
+	for _, key := range keys {
+		ret = append(ret, NewStatRow(key, s.sketches[key]))
+	}
+	return ret
+}
+
+// ------------- stat row -------------- //
+
+// ClassStats as the Item.Class() of the statistics
+const ClassStats = "stats"
+
+// StatQuantile as a quantile reported by a StatRow under the name
+type StatQuantile struct {
+	Name string
+	Q    float64
+}
+
+// StatQuantiles as the quantiles reported by a StatRow
+var StatQuantiles = []StatQuantile{
+	{"p50", 0.5},
+	{"p90", 0.9},
+	{"p99", 0.99},
+	{"p999", 0.999},
+}
+
+var _ Item = (*StatRow)(nil)
+
+// StatRow as the summary of one sketch
+type StatRow struct {
+	key       StatKey
+	count     int
+	min       time.Duration
+	max       time.Duration
+	mean      time.Duration
+	stddev    time.Duration
+	quantiles []time.Duration
+}
+
+func NewStatRow(key StatKey, sk *Sketch) *StatRow {
+	qs := make([]time.Duration, 0, len(StatQuantiles))
+	for _, q := range StatQuantiles {
+		qs = append(qs, sk.Quantile(q.Q))
+	}
+	return &StatRow{
+		key:       key,
+		count:     sk.Count(),
+		min:       sk.Min(),
+		max:       sk.Max(),
+		mean:      sk.Mean(),
+		stddev:    sk.StdDev(),
+		quantiles: qs,
+	}
+}
+
+func (i *StatRow) durations() []Field {
+	ret := []Field{
+		DurationField("min", i.min),
+		DurationField("max", i.max),
+		DurationField("mean", i.mean),
+		DurationField("stddev", i.stddev),
+	}
+	for idx, q := range StatQuantiles {
+		ret = append(ret, DurationField(q.Name, i.quantiles[idx]))
+	}
+	return ret
+}
+
+func (i *StatRow) Data() string {
+	parts := []string{i.key.Metric, i.key.Group, "count=" + strconv.Itoa(i.count)}
+	for _, f := range i.durations() {
+		parts = append(parts, fmt.Sprintf("%s=%s", f.Name, f.Duration()))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (i *StatRow) Header() []string {
+	ret := []string{"metric", "group", "count"}
+	for _, f := range i.durations() {
+		ret = append(ret, f.Name)
+	}
+	return ret
+}
+
+// Format keeps the fraction of the milliseconds, the querier costs are often
+// below one
+func (i *StatRow) Format() []string {
+	ret := []string{i.key.Metric, i.key.Group, strconv.Itoa(i.count)}
+	for _, f := range i.durations() {
+		ms := float64(f.Duration()) / float64(time.Millisecond)
+		ret = append(ret, strconv.FormatFloat(ms, 'f', 3, 64))
+	}
+	return ret
+}
+
+func (i *StatRow) Fields() []Field {
+	ret := []Field{
+		StringField("metric", i.key.Metric),
+		StringField("group", i.key.Group),
+		IntField("count", i.count),
+	}
+	return append(ret, i.durations()...)
+}
+
+func (i *StatRow) Stamp() time.Time {
+	return time.Time{}
+}
+
+func (i StatRow) Class() string {
+	return ClassStats
+}
+
+func (i StatRow) Level() ItemLevel {
+	return LevelInfo
+}
@@ -0,0 +1,124 @@
+package logparser
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+// exactQuantile picks the quantile of the sorted durations by the rank the
+// Sketch uses
+func exactQuantile(sorted []time.Duration, q float64) time.Duration {
+	return sorted[int(q*float64(len(sorted)-1))]
+}
+
+func TestSketchQuantile(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	cases := []struct {
+		name string
+		gen  func(i int) time.Duration
+		n    int
+	}{
+		{"constant", func(int) time.Duration { return 11 * time.Millisecond }, 1000},
+		{"linear", func(i int) time.Duration { return time.Duration(i+1) * time.Millisecond }, 10000},
+		{"exponential", func(int) time.Duration { return time.Duration(rnd.ExpFloat64() * float64(time.Second)) }, 20000},
+		{"with zeros", func(i int) time.Duration { return time.Duration(i%4) * time.Millisecond }, 1000},
+		{"single", func(int) time.Duration { return 3 * time.Second }, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sk := NewSketch()
+			values := make([]time.Duration, 0, c.n)
+			for i := 0; i < c.n; i++ {
+				d := c.gen(i)
+				values = append(values, d)
+				sk.Add(d)
+			}
+			sort.Slice(values, func(a, b int) bool { return values[a] < values[b] })
+
+			if sk.Count() != c.n {
+				t.Fatalf("count %d, want %d", sk.Count(), c.n)
+			}
+			if sk.Min() != values[0] || sk.Max() != values[c.n-1] {
+				t.Fatalf("min/max %s/%s, want %s/%s", sk.Min(), sk.Max(), values[0], values[c.n-1])
+			}
+			for _, q := range []float64{0, 0.5, 0.9, 0.99, 0.999, 1} {
+				got, want := sk.Quantile(q), exactQuantile(values, q)
+				if math.Abs(float64(got-want)) > SketchAccuracy*float64(want) {
+					t.Errorf("q%v: got %s, want %s within %v", q, got, want, SketchAccuracy)
+				}
+			}
+		})
+	}
+}
+
+func TestSketchEmpty(t *testing.T) {
+	sk := NewSketch()
+	if sk.Count() != 0 || sk.Quantile(0.5) != 0 || sk.StdDev() != 0 {
+		t.Fatalf("empty sketch: count %d p50 %s stddev %s", sk.Count(), sk.Quantile(0.5), sk.StdDev())
+	}
+}
+
+func TestSketchMerge(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	cases := []struct {
+		name  string
+		parts []int
+	}{
+		{"two halves", []int{500, 500}},
+		{"uneven", []int{1, 10, 1000}},
+		{"empty part", []int{0, 300, 0}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			whole, merged := NewSketch(), NewSketch()
+			for _, n := range c.parts {
+				part := NewSketch()
+				for i := 0; i < n; i++ {
+					d := time.Duration(rnd.Int63n(int64(time.Second)))
+					part.Add(d)
+					whole.Add(d)
+				}
+				merged.Merge(part)
+			}
+
+			if merged.Count() != whole.Count() || merged.Min() != whole.Min() || merged.Max() != whole.Max() {
+				t.Fatalf("merged count/min/max %d/%s/%s, want %d/%s/%s",
+					merged.Count(), merged.Min(), merged.Max(), whole.Count(), whole.Min(), whole.Max())
+			}
+			// the moments merge up to the rounding
+			if d := merged.Mean() - whole.Mean(); d < -time.Microsecond || d > time.Microsecond {
+				t.Errorf("mean %s, want %s", merged.Mean(), whole.Mean())
+			}
+			if d := merged.StdDev() - whole.StdDev(); d < -time.Microsecond || d > time.Microsecond {
+				t.Errorf("stddev %s, want %s", merged.StdDev(), whole.StdDev())
+			}
+			for _, q := range []float64{0.5, 0.9, 0.99} {
+				if got, want := merged.Quantile(q), whole.Quantile(q); got != want {
+					t.Errorf("q%v: got %s, want %s", q, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStatsSkipFirstCommit(t *testing.T) {
+	s := NewTMState()
+	stats := NewStats()
+	base := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	for h := 1; h <= 3; h++ {
+		stats.Add(s.commit(base.Add(time.Duration(h)*time.Second), h, 0, "AB"))
+	}
+
+	sk := stats.Sketch(StatKey{Metric: "tmCommit.block_cost"})
+	if sk == nil || sk.Count() != 2 {
+		t.Fatalf("block costs counted %v, want the 2 after the first commit", sk)
+	}
+	if sk.Max() != time.Second {
+		t.Fatalf("max block cost %s, want 1s", sk.Max())
+	}
+}
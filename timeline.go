@@ -0,0 +1,217 @@
+package logparser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ------------- consensus timeline -------------- //
+
+// ClassBlockTimeline as the Item.Class() of the per height timelines
+const ClassBlockTimeline = "blockTimeline"
+
+// timelinePhases as the steps of a height in order, the stamps of the
+// consensus steps are taken from the round which committed
+var timelinePhases = []string{StepPropose, StepPrevote, StepPrecommit, StepCommit, itemNameApply, itemNameCommit}
+
+// timelineCosts names the durations between the timelinePhases
+var timelineCosts = []string{
+	"propose_to_prevote",
+	"prevote_to_precommit",
+	"precommit_to_commit",
+	"commit_to_executed",
+	"executed_to_committed",
+}
+
+var _ Item = (*BlockTimeline)(nil)
+
+// BlockTimeline breaks the time of one height down into its phases, from
+// entering the propose step of the committed round over the votes and the
+// commit until the block executed and the state committed. total as the time
+// from the first new round of the height until the state committed, a phase
+// with a step missing from the log costs zero
+type BlockTimeline struct {
+	height   int
+	stamp    time.Time
+	rounds   int
+	timeouts int
+	costs    []time.Duration
+	total    time.Duration
+}
+
+func (i *BlockTimeline) Data() string {
+	return fmt.Sprintf("height=%d rounds=%d timeouts=%d costs=%v total=%s", i.height, i.rounds, i.timeouts, i.costs, i.total)
+}
+
+func (i *BlockTimeline) Header() []string {
+	ret := []string{"height", "stamp", "rounds", "timeouts"}
+	ret = append(ret, timelineCosts...)
+	return append(ret, "total_cost")
+}
+
+func (i *BlockTimeline) Format() []string {
+	stamp := ""
+	if !i.stamp.IsZero() {
+		stamp = i.stamp.Format(time.RFC3339)
+	}
+	ret := []string{strconv.Itoa(i.height), stamp, strconv.Itoa(i.rounds), strconv.Itoa(i.timeouts)}
+	for _, c := range i.costs {
+		ret = append(ret, strconv.FormatInt(c.Milliseconds(), 10))
+	}
+	return append(ret, strconv.FormatInt(i.total.Milliseconds(), 10))
+}
+
+func (i *BlockTimeline) Fields() []Field {
+	ret := []Field{
+		IntField("height", i.height),
+		TimeField("stamp", i.stamp),
+		IntField("rounds", i.rounds),
+		IntField("timeouts", i.timeouts),
+	}
+	for idx, name := range timelineCosts {
+		ret = append(ret, DurationField(name, i.costs[idx]))
+	}
+	return append(ret, DurationField("total_cost", i.total))
+}
+
+func (i *BlockTimeline) Stamp() time.Time {
+	return i.stamp
+}
+
+func (i BlockTimeline) Class() string {
+	return ClassBlockTimeline
+}
+
+func (i BlockTimeline) Level() ItemLevel {
+	return LevelInfo
+}
+
+// heightSteps gathers the stamps of the steps of one height
+type heightSteps struct {
+	// first stamp of every consensus step by round
+	rounds      map[int]map[string]time.Time
+	maxRound    int
+	commitRound int
+	committing  bool
+	timeouts    int
+	// executed and committed stamps
+	applied map[string]time.Time
+}
+
+func (h *heightSteps) stepAt(round int, step string, stamp time.Time) {
+	steps, ok := h.rounds[round]
+	if !ok {
+		steps = map[string]time.Time{}
+		h.rounds[round] = steps
+	}
+	if _, ok := steps[step]; !ok {
+		steps[step] = stamp
+	}
+	if round > h.maxRound {
+		h.maxRound = round
+	}
+}
+
+func since(from, to time.Time) time.Duration {
+	if from.IsZero() || to.IsZero() {
+		return 0
+	}
+	return to.Sub(from)
+}
+
+func (h *heightSteps) timeline(height int) *BlockTimeline {
+	round := h.maxRound
+	if h.committing {
+		round = h.commitRound
+	}
+
+	stamps := make([]time.Time, 0, len(timelinePhases))
+	for _, phase := range timelinePhases {
+		if stamp, ok := h.applied[phase]; ok {
+			stamps = append(stamps, stamp)
+		} else {
+			stamps = append(stamps, h.rounds[round][phase])
+		}
+	}
+
+	costs := make([]time.Duration, 0, len(timelineCosts))
+	for idx := range timelineCosts {
+		costs = append(costs, since(stamps[idx], stamps[idx+1]))
+	}
+
+	start := h.rounds[0][StepNewRound]
+	if start.IsZero() {
+		start = h.rounds[0][StepPropose]
+	}
+	committed := h.applied[itemNameCommit]
+
+	rounds := 0
+	if len(h.rounds) > 0 {
+		rounds = h.maxRound + 1
+	}
+	return &BlockTimeline{
+		height:   height,
+		stamp:    committed,
+		rounds:   rounds,
+		timeouts: h.timeouts,
+		costs:    costs,
+		total:    since(start, committed),
+	}
+}
+
+// BuildTimeline reconstructs the consensus timeline of every height from the
+// consensus steps, the executed blocks and the committed states, returns one
+// BlockTimeline per height in height order
+func BuildTimeline(res ParseResult) []Item {
+	heights := map[int]*heightSteps{}
+	at := func(h int) *heightSteps {
+		steps, ok := heights[h]
+		if !ok {
+			steps = &heightSteps{
+				rounds:  map[int]map[string]time.Time{},
+				applied: map[string]time.Time{},
+			}
+			heights[h] = steps
+		}
+		return steps
+	}
+
+	for _, items := range res {
+		for _, item := range items {
+			switch i := item.(type) {
+			case *TMInfoConsensus:
+				steps := at(i.height)
+				switch i.step {
+				case StepTimeout:
+					steps.timeouts++
+				case StepProposal:
+				default:
+					steps.stepAt(i.round, i.step, i.stamp)
+					if i.step == StepCommit {
+						steps.committing = true
+						steps.commitRound = i.round
+					}
+				}
+			case *TMInfoApply:
+				at(i.height).applied[itemNameApply] = i.stamp
+			case *TMInfoCommit:
+				at(i.height).applied[itemNameCommit] = i.stamp
+			default:
+			}
+		}
+	}
+
+	sorted := make([]int, 0, len(heights))
+	for h := range heights {
+		sorted = append(sorted, h)
+	}
+	sort.Ints(sorted)
+
+	ret := make([]Item, 0, len(sorted))
+	for _, h := range sorted {
+		ret = append(ret, heights[h].timeline(h))
+	}
+	return ret
+}
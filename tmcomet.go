@@ -0,0 +1,315 @@
+package logparser
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ------------- cometbft format -------------- //
+
+// the levels of the cometbft plain format
+const (
+	CometLevelInfo  = "INF"
+	CometLevelErr   = "ERR"
+	CometLevelDebug = "DBG"
+	CometLevelWarn  = "WRN"
+)
+
+// cometLevels maps the levels of the format to the item levels
+var cometLevels = map[string]ItemLevel{
+	CometLevelInfo:  LevelInfo,
+	CometLevelErr:   LevelErr,
+	CometLevelDebug: LevelDbg,
+	CometLevelWarn:  LevelWarn,
+}
+
+// CometDateFmt as the layout of the date of the logs, given or found in the
+// file name
+const CometDateFmt = "2006-01-02"
+
+var (
+	// like `3:04PM INF executed block height=123 module=state num_valid_txs=1`
+	cometLineRegex = regexp.MustCompile(`^(?P<stamp>\d\S*) (?P<level>INF|ERR|DBG|WRN) (?P<rest>.*)$`)
+	// the first `KEY=` ends the message
+	cometKeyRegex  = regexp.MustCompile(`(?:^|\s)[A-Za-z_][\w.\-]*=`)
+	cometDateRegex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+)
+
+// cometStampFmts as the stamp layouts of the format, the kitchen time being
+// the default of cometbft, a full stamp carries its own date
+var cometStampFmts = []string{time.Kitchen, "15:04:05.000", "15:04:05", "15:04"}
+
+// cometNames maps the lower case messages of cometbft to the item names
+var cometNames = func() map[string]string {
+	ret := map[string]string{}
+	for _, name := range []string{itemNameApply, itemNameCommit, itemNameEndBlocker, itemNameHandler, itemNameQuerier, itemNameTimeout, itemNameProposal} {
+		ret[strings.ToLower(name)] = name
+	}
+	return ret
+}()
+
+// cometSteps maps the messages of entering a consensus step to the steps
+var cometSteps = map[string]string{
+	"entering new round":      StepNewRound,
+	"entering propose step":   StepPropose,
+	"entering prevote step":   StepPrevote,
+	"entering precommit step": StepPrecommit,
+	"entering commit step":    StepCommit,
+}
+
+// cometDate tracks the day the stamps of the cometbft format fall on
+type cometDate struct {
+	// given as the date of every log, else taken from the file name
+	given time.Time
+
+	source string
+	day    time.Time
+	last   time.Time
+	// first as the first stamp dated by the time of day
+	first time.Time
+}
+
+// SetLogDate sets the date of the logs in the cometbft plain format, which
+// omits it from the stamps, overriding the date in the file names
+func (s *TMState) SetLogDate(d time.Time) {
+	s.date.given = d
+}
+
+func (s *TMState) LogDate() time.Time {
+	return s.date.given
+}
+
+// startFile resets the day to the date in the name of the file, with the log
+// date given the files of a set follow each other like a rotated set and the
+// day carries over from the previous file
+func (d *cometDate) startFile(source string) error {
+	d.source = source
+	if !d.given.IsZero() {
+		if d.day.IsZero() {
+			d.day = d.given
+		}
+		return nil
+	}
+
+	d.last = time.Time{}
+	found := cometDateRegex.FindString(filepath.Base(source))
+	if len(found) == 0 {
+		d.day = time.Time{}
+		return fmt.Errorf("no date of %s, give the log date", source)
+	}
+	day, err := time.Parse(CometDateFmt, found)
+	if err != nil {
+		return fmt.Errorf("error parse date of %s: %s", source, err.Error())
+	}
+	d.day = day
+	return nil
+}
+
+// stamp places the time of day on the current day, moving on to the next day
+// when the clock went back by more than half a day
+func (d *cometDate) stamp(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return t, nil
+	}
+
+	var (
+		tod time.Time
+		err error
+	)
+	for _, layout := range cometStampFmts {
+		if tod, err = time.Parse(layout, raw); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parse timestamp(%s): %s", raw, err.Error())
+	}
+	if d.day.IsZero() {
+		return time.Time{}, fmt.Errorf("no date for timestamp(%s), give the log date", raw)
+	}
+
+	at := func() time.Time {
+		return time.Date(d.day.Year(), d.day.Month(), d.day.Day(), tod.Hour(), tod.Minute(), tod.Second(), tod.Nanosecond(), time.UTC)
+	}
+	stamp := at()
+	if !d.last.IsZero() && stamp.Before(d.last.Add(-12*time.Hour)) {
+		d.day = d.day.AddDate(0, 0, 1)
+		stamp = at()
+	}
+	if d.first.IsZero() {
+		d.first = stamp
+	}
+	d.last = stamp
+	return stamp, nil
+}
+
+// follows tells whether the stamps of the chunk continue the day of d, rather
+// than starting over from the date in the name of another file
+func (d *cometDate) follows(chunk *cometDate) bool {
+	return !d.last.IsZero() && (chunk.source == d.source || !d.given.IsZero())
+}
+
+// resolveChunk moves the stamps of a chunk, dated from the start of the file
+// or the log date, on by the days passed before the chunk: a chunk starting
+// more than half a day before the previous one ended is a day later. d then
+// moves to the day of the end of the chunk
+func (d *cometDate) resolveChunk(chunk *TMState, items []Item) {
+	cd := &chunk.date
+	if cd.first.IsZero() {
+		return
+	}
+
+	if d.follows(cd) {
+		shift := time.Duration(0)
+		for cd.first.Add(shift).Before(d.last.Add(-12 * time.Hour)) {
+			shift += 24 * time.Hour
+		}
+		if shift > 0 {
+			for _, item := range items {
+				shiftStamp(item, shift)
+			}
+			if chunk.committed {
+				chunk.heightStamp = chunk.heightStamp.Add(shift)
+			}
+			cd.day = cd.day.Add(shift)
+			cd.last = cd.last.Add(shift)
+		}
+	}
+
+	d.source = cd.source
+	d.day = cd.day
+	d.last = cd.last
+}
+
+// shiftStamp moves the stamp of a tendermint item on by shift
+func shiftStamp(item Item, shift time.Duration) {
+	switch i := item.(type) {
+	case *TMItemErr:
+		i.stamp = i.stamp.Add(shift)
+	case *TMInfoApply:
+		i.stamp = i.stamp.Add(shift)
+	case *TMInfoCommit:
+		i.stamp = i.stamp.Add(shift)
+	case *TMInfoEndBlocker:
+		i.stamp = i.stamp.Add(shift)
+	case *TMInfoHandler:
+		i.stamp = i.stamp.Add(shift)
+	case *TMInfoQuerier:
+		i.stamp = i.stamp.Add(shift)
+	case *TMInfoIgnore:
+		i.stamp = i.stamp.Add(shift)
+	case *TMInfoConsensus:
+		i.stamp = i.stamp.Add(shift)
+	default:
+	}
+}
+
+// ------------- register -------------- //
+
+// RegisterTMComet registers the parser of the cometbft plain format, the
+// stamps are dated by the file name or the log date of the TMState and move
+// on a day past every midnight
+func (p *Parser) RegisterTMComet() error {
+	return p.registerRegex(cometLineRegex.String(), 0, func(p *Parser, lineNum int, _ string, groups map[string]string) (Item, error) {
+		if p.source != p.tm.date.source || p.tm.date.day.IsZero() {
+			// the missing date fails the first line of the file
+			if err := p.tm.date.startFile(p.source); err != nil {
+				return nil, fmt.Errorf("%d: %s", lineNum, err.Error())
+			}
+		}
+		return p.tm.parseComet(lineNum, groups["stamp"], groups["level"], groups["rest"])
+	})
+}
+
+func RegisterTMComet() {
+	if err := defaultParser.RegisterTMComet(); err != nil {
+		panic(err)
+	}
+}
+
+// ParseComet parses a line of the cometbft plain format, dated by the log date
+func (s *TMState) ParseComet(lineNum int, lineText string) (Item, error) {
+	m := cometLineRegex.FindStringSubmatch(lineText)
+	if m == nil {
+		return nil, fmt.Errorf("%d: malformed comet item: %s", lineNum, lineText)
+	}
+	if s.date.day.IsZero() {
+		s.date.day = s.date.given
+	}
+	return s.parseComet(lineNum, m[1], m[2], m[3])
+}
+
+func ParseTMComet(lineNum int, lineText string) (Item, error) {
+	return defaultParser.tm.ParseComet(lineNum, lineText)
+}
+
+// splitCometRest splits the message from the `KEY=VALUE` pairs following it
+func splitCometRest(rest string) (string, string) {
+	loc := cometKeyRegex.FindStringIndex(rest)
+	if loc == nil {
+		return strings.TrimSpace(rest), ""
+	}
+	return strings.TrimSpace(rest[:loc[0]]), strings.TrimSpace(rest[loc[0]:])
+}
+
+func (s *TMState) parseComet(lineNum int, rawStamp, level, rest string) (Item, error) {
+	stamp, err := s.date.stamp(rawStamp)
+	if err != nil {
+		return nil, fmt.Errorf("%d: %s", lineNum, err.Error())
+	}
+
+	msg, tail := splitCometRest(rest)
+	fields, err := parseTailFields(tail)
+	if level == CometLevelErr {
+		if err != nil {
+			return NewTMItemErr(stamp, lineNum, s.height, msg, tail), nil
+		}
+		return NewTMItemErr(stamp, lineNum, s.height, msg, fields.tail()), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%d: error split tail: %s", lineNum, err.Error())
+	}
+
+	if step, ok := cometSteps[strings.ToLower(msg)]; ok {
+		ret, err := buildConsensusStep(stamp, step, fields)
+		if err != nil {
+			return nil, fmt.Errorf("%d: error parse fields: %s", lineNum, err.Error())
+		}
+		return ret, nil
+	}
+
+	name := msg
+	if known, ok := cometNames[strings.ToLower(msg)]; ok {
+		name = known
+	}
+	ret, err := s.buildInfo(stamp, name, fields)
+	if err != nil {
+		return nil, fmt.Errorf("%d: error parse fields: %s", lineNum, err.Error())
+	}
+	// the lines ignored keep the debug or warn level
+	if ignored, ok := ret.(*TMInfoIgnore); ok {
+		ignored.level = cometLevels[level]
+	}
+	return ret, nil
+}
+
+// buildConsensusStep makes the consensus item of entering the step, current
+// like `2/0/RoundStepPropose`
+func buildConsensusStep(stamp time.Time, step string, f tmFields) (Item, error) {
+	h, err := f.int("height")
+	if err != nil {
+		return nil, fmt.Errorf("error parse %s height: %s", step, err.Error())
+	}
+	r, err := f.int("round")
+	if err != nil {
+		return nil, fmt.Errorf("error parse %s round: %s", step, err.Error())
+	}
+	current, _ := f.lookup("current")
+	if idx := strings.LastIndex(current, "/"); idx >= 0 {
+		current = current[idx+1:]
+	}
+	return withExtra(NewTMInfoConsensus(stamp, h, r, step, current, 0), f), nil
+}
@@ -0,0 +1,34 @@
+package logparser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCometLevels(t *testing.T) {
+	cases := []struct {
+		line  string
+		class string
+		level ItemLevel
+	}{
+		{"10:00:00.000 INF committed state app_hash=AB height=2 module=state num_txs=1", "tmCommit", LevelInfo},
+		{"10:00:00.000 INF service start module=p2p", "tmIgnore", LevelInfo},
+		{"10:00:00.000 WRN dialing failed module=p2p err=timeout", "tmIgnore", LevelWarn},
+		{"10:00:00.000 DBG signed and pushed vote module=consensus", "tmIgnore", LevelDbg},
+		{"10:00:00.000 ERR stopping peer for error module=p2p err=EOF", "tmErr", LevelErr},
+	}
+
+	for _, c := range cases {
+		t.Run(c.line, func(t *testing.T) {
+			s := NewTMState()
+			s.SetLogDate(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC))
+			item, err := s.ParseComet(1, c.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Class() != c.class || item.Level() != c.level {
+				t.Fatalf("%s at %s, want %s at %s", item.Class(), item.Level().Str(), c.class, c.level.Str())
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package logparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ------------- consensus item -------------- //
+
+// consensus steps as the TMInfoConsensus.step values
+const (
+	StepNewRound  = "enterNewRound"
+	StepPropose   = "enterPropose"
+	StepPrevote   = "enterPrevote"
+	StepPrecommit = "enterPrecommit"
+	StepCommit    = "enterCommit"
+	StepTimeout   = "timedOut"
+	StepProposal  = "receivedProposal"
+)
+
+const (
+	itemNameTimeout  = "Timed out"
+	itemNameProposal = "Received proposal"
+)
+
+var (
+	// like `enterPrevote(2/0). Current: 2/0/RoundStepPropose`
+	enterStepRegex = regexp.MustCompile(`^(enter(?:NewRound|Propose|Prevote|Precommit|Commit))\((\d+)/(\d+)\)(?:\. Current: \d+/\d+/(\w+))?`)
+	// like `proposal="Proposal{2/0 (...) ...}"`
+	proposalRegex = regexp.MustCompile(`Proposal\{(\d+)/(\d+)`)
+)
+
+var _ Item = (*TMInfoConsensus)(nil)
+
+// TMInfoConsensus as a step of the consensus state machine: entering a step
+// of a round, a timeout of a step or the arrival of a proposal. current as the
+// step the state machine was in, timeout as the duration which timed out
+type TMInfoConsensus struct {
+	itemSource
+	tmExtra
+
+	stamp   time.Time
+	height  int
+	round   int
+	step    string
+	current string
+	timeout time.Duration
+}
+
+func NewTMInfoConsensus(s time.Time, h, r int, step, current string, timeout time.Duration) Item {
+	return &TMInfoConsensus{
+		stamp:   s,
+		height:  h,
+		round:   r,
+		step:    step,
+		current: current,
+		timeout: timeout,
+	}
+}
+
+// isConsensusItem tells the names of the consensus items
+func isConsensusItem(name string) bool {
+	return name == itemNameTimeout || name == itemNameProposal || enterStepRegex.MatchString(name)
+}
+
+func parseConsensus(stamp time.Time, name string, f tmFields) (Item, error) {
+	switch name {
+	case itemNameTimeout:
+		h, err := f.int("height")
+		if err != nil {
+			return nil, fmt.Errorf("error parse timeout height: %s", err.Error())
+		}
+		r, err := f.int("round")
+		if err != nil {
+			return nil, fmt.Errorf("error parse timeout round: %s", err.Error())
+		}
+		dur, err := f.duration("dur")
+		if err != nil {
+			return nil, fmt.Errorf("error parse timeout dur: %s", err.Error())
+		}
+		step, _ := f.lookup("step")
+		return withExtra(NewTMInfoConsensus(stamp, h, r, StepTimeout, step, dur), f), nil
+	case itemNameProposal:
+		proposal, _ := f.lookup("proposal")
+		m := proposalRegex.FindStringSubmatch(proposal)
+		if m == nil {
+			return nil, fmt.Errorf("malformed proposal: %s", proposal)
+		}
+		h, _ := strconv.Atoi(m[1])
+		r, _ := strconv.Atoi(m[2])
+		return withExtra(NewTMInfoConsensus(stamp, h, r, StepProposal, "", 0), f), nil
+	default:
+	}
+
+	m := enterStepRegex.FindStringSubmatch(name)
+	if m == nil {
+		return nil, fmt.Errorf("malformed consensus step: %s", name)
+	}
+	h, _ := strconv.Atoi(m[2])
+	r, _ := strconv.Atoi(m[3])
+	return withExtra(NewTMInfoConsensus(stamp, h, r, m[1], m[4], 0), f), nil
+}
+
+func (i *TMInfoConsensus) Data() string {
+	return fmt.Sprintf("I[%s] %-32s module=consensus height=%d round=%d current=%s", i.stamp.Format(TMStampFmt), i.step, i.height, i.round, i.current)
+}
+
+func (i TMInfoConsensus) Header() []string {
+	return []string{"height", "stamp", "round", "step", "current", "timeout"}
+}
+
+func (i *TMInfoConsensus) Format() []string {
+	asMS := strconv.FormatInt(i.timeout.Milliseconds(), 10)
+	return []string{strconv.Itoa(i.height), i.stamp.Format(time.RFC3339Nano), strconv.Itoa(i.round), i.step, i.current, asMS}
+}
+
+func (i *TMInfoConsensus) Fields() []Field {
+	return append([]Field{
+		IntField("height", i.height),
+		TimeField("stamp", i.stamp),
+		IntField("round", i.round),
+		StringField("step", i.step),
+		StringField("current", i.current),
+		DurationField("timeout", i.timeout),
+	}, i.extraFields()...)
+}
+
+func (i *TMInfoConsensus) Stamp() time.Time {
+	return i.stamp
+}
+
+func (i TMInfoConsensus) Class() string {
+	return "tmConsensus"
+}
+
+func (i TMInfoConsensus) Level() ItemLevel {
+	return LevelInfo
+}
@@ -0,0 +1,119 @@
+package logparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ------------- json format -------------- //
+
+// TMPrefixJSON as the prefix of the records logged with log_format = "json",
+// like `{"level":"info","module":"state","height":123,"_msg":"Executed block"}`
+const TMPrefixJSON = "{"
+
+const (
+	tmJSONLevel  = "level"
+	tmJSONMsg    = "_msg"
+	tmJSONErrLvl = "error"
+)
+
+// tmJSONStampKeys as the keys the stamp of a record goes by
+var tmJSONStampKeys = []string{"ts", "time", "_time"}
+
+func (p *Parser) RegisterTMJSON() error {
+	return p.registerPrefix(TMPrefixJSON, 0, func(p *Parser, lineNum int, lineText string, _ map[string]string) (Item, error) {
+		return p.tm.ParseJSON(lineNum, lineText)
+	})
+}
+
+func RegisterTMJSON() {
+	if err := defaultParser.RegisterTMJSON(); err != nil {
+		panic(err)
+	}
+}
+
+// jsonString gives a decoded value as it would be logged in the plain format
+func jsonString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		raw, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(raw)
+	}
+}
+
+// splitTMRecord decodes a json record into its stamp, level, message and the
+// remaining fields
+func splitTMRecord(lineText string) (time.Time, string, string, tmFields, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(lineText)))
+	dec.UseNumber()
+	rec := map[string]interface{}{}
+	if err := dec.Decode(&rec); err != nil {
+		return time.Time{}, "", "", nil, fmt.Errorf("malformed json record: %s", err.Error())
+	}
+
+	msg, ok := rec[tmJSONMsg].(string)
+	if !ok {
+		return time.Time{}, "", "", nil, fmt.Errorf("missing %s: %s", tmJSONMsg, lineText)
+	}
+	level := jsonString(rec[tmJSONLevel])
+
+	var stamp time.Time
+	for _, key := range tmJSONStampKeys {
+		raw, ok := rec[key]
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, jsonString(raw))
+		if err != nil {
+			return time.Time{}, "", "", nil, fmt.Errorf("error parse timestamp(%s): %s", jsonString(raw), err.Error())
+		}
+		stamp = t
+		delete(rec, key)
+		break
+	}
+
+	delete(rec, tmJSONMsg)
+	delete(rec, tmJSONLevel)
+	fields := tmFields{}
+	for key, v := range rec {
+		fields[key] = jsonString(v)
+	}
+	return stamp, level, msg, fields, nil
+}
+
+// ParseJSON parses a json record into the same items as the plain format, a
+// record without a stamp is parsed with the zero time
+func (s *TMState) ParseJSON(lineNum int, lineText string) (Item, error) {
+	stamp, level, name, fields, err := splitTMRecord(lineText)
+	if err != nil {
+		return nil, fmt.Errorf("%d: %s", lineNum, err.Error())
+	}
+
+	if level == tmJSONErrLvl {
+		return NewTMItemErr(stamp, lineNum, s.height, name, fields.tail()), nil
+	}
+
+	ret, err := s.buildInfo(stamp, name, fields)
+	if err != nil {
+		return nil, fmt.Errorf("%d: error parse fields: %s", lineNum, err.Error())
+	}
+	return ret, nil
+}
+
+func ParseTMJSON(lineNum int, lineText string) (Item, error) {
+	return defaultParser.tm.ParseJSON(lineNum, lineText)
+}
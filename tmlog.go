@@ -2,6 +2,8 @@ package logparser
 
 import (
 	"fmt"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -17,39 +19,120 @@ const (
 	TMStampFmt  = "2006-01-02|15:04:05.000"
 )
 
-var (
-	currentHeight      = 0
-	currentHeightStamp = time.Time{}
-)
+// TMState tracks the height of the latest committed block, the items which do
+// not carry a height of their own are attributed to it
+type TMState struct {
+	height      int
+	heightStamp time.Time
+
+	// committed is set once a commit is seen or its stamp is set, before that
+	// the height is not known when parsing from the middle of a log
+	committed bool
+
+	// date dates the stamps of the cometbft plain format
+	date cometDate
+}
+
+func NewTMState() *TMState {
+	return &TMState{}
+}
+
+func (s *TMState) SetHeight(h int) {
+	s.height = h
+}
+
+// SetHeightStamp sets the stamp of the latest commit, the cost of the next
+// commit counts from it even when no commit was parsed yet
+func (s *TMState) SetHeightStamp(t time.Time) {
+	s.heightStamp = t
+	s.committed = true
+}
+
+func (s *TMState) Height() int {
+	return s.height
+}
+
+func (s *TMState) HeightStamp() time.Time {
+	return s.heightStamp
+}
+
+// reset forgets the state of the previous log before an unrelated one, only
+// the log date given for every log is kept
+func (s *TMState) reset() {
+	*s = TMState{
+		date: cometDate{given: s.date.given},
+	}
+}
+
+// resolveChunk fixes up the items parsed from a chunk in the middle of a log,
+// where the chunk state started out unknown: s as the state at the end of the
+// previous chunk supplies the height of the items before the first commit and
+// the stamp the first commit cost counts from, and the day the cometbft stamps
+// of the chunk fall on. s then moves to the end of the chunk
+func (s *TMState) resolveChunk(chunk *TMState, items []Item) {
+	s.date.resolveChunk(chunk, items)
+
+	for _, item := range items {
+		if commit, ok := item.(*TMInfoCommit); ok {
+			commit.setCostFrom(s.heightStamp, s.committed)
+			break
+		}
+
+		switch i := item.(type) {
+		case *TMItemErr:
+			i.height = s.height
+		case *TMInfoQuerier:
+			i.height = s.height
+		case *TMInfoIgnore:
+			i.height = s.height
+		default:
+		}
+	}
+
+	if chunk.committed {
+		s.height = chunk.height
+		s.heightStamp = chunk.heightStamp
+		s.committed = true
+	}
+}
 
 func SetCurrentHeight(h int) {
-	currentHeight = h
+	defaultParser.tm.SetHeight(h)
 }
 
 func SetCurrentHeightStamp(s time.Time) {
-	currentHeightStamp = s
+	defaultParser.tm.SetHeightStamp(s)
 }
 
 // ------------- register -------------- //
 
-func RegisterTMPrefix() {
-	if err := RegisterPrefixClassifier(TMPrefixErr, ParseTMErr); err != nil {
-		panic(err)
+func (p *Parser) RegisterTMPrefix() error {
+	err := p.registerPrefix(TMPrefixErr, 0, func(p *Parser, lineNum int, lineText string, _ map[string]string) (Item, error) {
+		return p.tm.ParseErr(lineNum, lineText)
+	})
+	if err != nil {
+		return err
 	}
-	if err := RegisterPrefixClassifier(TMPrefixInfo, ParseTMInfo); err != nil {
+	return p.registerPrefix(TMPrefixInfo, 0, func(p *Parser, lineNum int, lineText string, _ map[string]string) (Item, error) {
+		return p.tm.ParseInfo(lineNum, lineText)
+	})
+}
+
+func RegisterTMPrefix() {
+	if err := defaultParser.RegisterTMPrefix(); err != nil {
 		panic(err)
 	}
 }
 
 func splitTMItem(lineText string) (time.Time, string, string, error) {
-	parts := strings.Split(lineText, TMItemSep)
+	parts := strings.SplitN(lineText, TMItemSep, 2)
 	if len(parts) != 2 {
 		return time.Time{}, "", "", fmt.Errorf("malformed item: %s", lineText)
 	}
 
 	// parse head
-	headParts := strings.Split(parts[0], "]")
-	if len(parts) != 2 {
+	headParts := strings.SplitN(parts[0], "]", 2)
+	if len(headParts) != 2 {
 		return time.Time{}, "", "", fmt.Errorf("malformed head: %s", parts[0])
 	}
 
@@ -66,11 +149,140 @@ func parseTMStamp(lineHead string) (time.Time, error) {
 	return time.Parse(TMStampFmt, strings.TrimLeft(lineHead, TMStampTrim))
 }
 
+// ------------- tail fields -------------- //
+
+// tmModuleKey as the key of the module, the first pair of a tail
+const tmModuleKey = "module"
+
+// tmFields as the `KEY=VALUE` pairs of an item, the values as logged
+type tmFields map[string]string
+
+// parseTailFields tokenizes the logfmt pairs of a tail, the last of the pairs
+// of a key wins
+func parseTailFields(tail string) (tmFields, error) {
+	pairs, err := parseLogfmt(tail)
+	if err != nil {
+		return nil, err
+	}
+	ret := tmFields{}
+	for _, pair := range pairs {
+		ret[pair.key] = pair.value
+	}
+	return ret, nil
+}
+
+// lookup takes the value of the first of the keys present, the keys of an
+// item differ between the tendermint versions. The key taken is removed, so
+// that the pairs left over are the extra fields of the item
+func (f tmFields) lookup(keys ...string) (string, error) {
+	for _, key := range keys {
+		if v, ok := f[key]; ok {
+			delete(f, key)
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("missing key %s", keys[0])
+}
+
+func (f tmFields) int(keys ...string) (int, error) {
+	v, err := f.lookup(keys...)
+	if err != nil {
+		return -1, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return -1, fmt.Errorf("error parse int: %s", err.Error())
+	}
+	return n, nil
+}
+
+// tmMillisRegex as a bare decimal number, taken as milliseconds
+var tmMillisRegex = regexp.MustCompile(`^-?(\d+(\.\d*)?|\.\d+)$`)
+
+// duration reads a value like `12ms`, a bare decimal number as milliseconds
+func (f tmFields) duration(keys ...string) (time.Duration, error) {
+	v, err := f.lookup(keys...)
+	if err != nil {
+		return 0, err
+	}
+	if tmMillisRegex.MatchString(v) {
+		ms, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.Abs(ms) >= float64(math.MaxInt64/int64(time.Millisecond)) {
+			return 0, fmt.Errorf("error parse duration: %s ms out of range", v)
+		}
+		return time.Duration(ms * float64(time.Millisecond)), nil
+	}
+	dur, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("error parse duration: %s", err.Error())
+	}
+	return dur, nil
+}
+
+// rest returns the pairs no field took, the module aside
+func (f tmFields) rest() map[string]string {
+	ret := map[string]string{}
+	for key, v := range f {
+		if key != tmModuleKey {
+			ret[key] = v
+		}
+	}
+	return ret
+}
+
+// tail formats the fields like the tail of the plain format, the module first
+// and the other keys sorted
+func (f tmFields) tail() string {
+	rest := f.rest()
+	if len(rest) == 0 {
+		return f[tmModuleKey]
+	}
+	return f[tmModuleKey] + " " + formatLogfmt(rest)
+}
+
+type extraSetter interface {
+	setExtra(map[string]string)
+}
+
+// tmExtra keeps the pairs of the tail the item has no field for, exported as
+// the logfmt `extra` field when there are any
+type tmExtra struct {
+	extra map[string]string
+}
+
+func (e *tmExtra) setExtra(extra map[string]string) {
+	e.extra = extra
+}
+
+// Extra returns the pairs of the tail the item has no field for
+func (e *tmExtra) Extra() map[string]string {
+	return e.extra
+}
+
+func (e *tmExtra) extraFields() []Field {
+	if len(e.extra) == 0 {
+		return nil
+	}
+	return []Field{StringField("extra", formatLogfmt(e.extra))}
+}
+
+// withExtra keeps the pairs left over in the item
+func withExtra(item Item, f tmFields) Item {
+	if s, ok := item.(extraSetter); ok {
+		if rest := f.rest(); len(rest) > 0 {
+			s.setExtra(rest)
+		}
+	}
+	return item
+}
+
 // ------------- error item -------------- //
 
 var _ Item = (*TMItemErr)(nil)
 
 type TMItemErr struct {
+	itemSource
+
 	line   int
 	stamp  time.Time
 	height int
@@ -90,7 +302,7 @@ func NewTMItemErr(t time.Time, l, h int, n, i string) Item {
 }
 
 func (e *TMItemErr) Data() string {
-	return fmt.Sprintf("E[%s] %-32s module=%s %s", e.stamp.Format(TMStampFmt), e.name, e.info)
+	return fmt.Sprintf("E[%s] %-32s module=%s", e.stamp.Format(TMStampFmt), e.name, e.info)
 }
 
 func (e TMItemErr) Header() []string {
@@ -101,6 +313,16 @@ func (e *TMItemErr) Format() []string {
 	return []string{e.stamp.Format(time.RFC3339), strconv.Itoa(e.line), strconv.Itoa(e.height), e.name, e.info}
 }
 
+func (e *TMItemErr) Fields() []Field {
+	return []Field{
+		TimeField("stamp", e.stamp),
+		IntField("line", e.line),
+		IntField("height", e.height),
+		StringField("name", e.name),
+		StringField("detail", e.info),
+	}
+}
+
 func (e *TMItemErr) Stamp() time.Time {
 	return e.stamp
 }
@@ -113,13 +335,17 @@ func (e TMItemErr) Level() ItemLevel {
 	return LevelErr
 }
 
-func ParseTMErr(lineNum int, lineText string) (Item, error) {
+func (s *TMState) ParseErr(lineNum int, lineText string) (Item, error) {
 	stamp, name, tail, err := splitTMItem(lineText)
 	if err != nil {
 		return nil, fmt.Errorf("[%d] error split err log: %s", lineNum, err.Error())
 	}
 
-	return NewTMItemErr(stamp, lineNum, currentHeight, name, tail), nil
+	return NewTMItemErr(stamp, lineNum, s.height, name, tail), nil
+}
+
+func ParseTMErr(lineNum int, lineText string) (Item, error) {
+	return defaultParser.tm.ParseErr(lineNum, lineText)
 }
 
 // ------------- info item ---------------- //
@@ -130,6 +356,9 @@ const itemNameApply = "Executed block"
 var _ Item = (*TMInfoApply)(nil)
 
 type TMInfoApply struct {
+	itemSource
+	tmExtra
+
 	height       int
 	validTxNum   int
 	invalidTxNum int
@@ -145,41 +374,23 @@ func NewTmInfoApply(h, vtxn, itxn int, s time.Time) Item {
 	}
 }
 
-// form like `NAME=INT_VALUE`
-func fetchIntFromPair(pair string) (int, error) {
-	parts := strings.Split(pair, "=")
-	if len(parts) != 2 {
-		return -1, fmt.Errorf("malformed pair: %s", pair)
-	}
-	n, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return -1, fmt.Errorf("error parse int: %s", err.Error())
-	}
-	return n, nil
-}
-
-func parseTailApply(stamp time.Time, tail string) (Item, error) {
-	parts := strings.Split(tail, " ")
-	if len(parts) != 4 {
-		return nil, fmt.Errorf("malformed apply tail: %s", tail)
-	}
-
-	h, err := fetchIntFromPair(parts[1])
+func parseTailApply(stamp time.Time, f tmFields) (Item, error) {
+	h, err := f.int("height")
 	if err != nil {
 		return nil, fmt.Errorf("error parse apply height: %s", err.Error())
 	}
 
-	vtxs, err := fetchIntFromPair(parts[2])
+	vtxs, err := f.int("validTxs", "num_valid_txs")
 	if err != nil {
 		return nil, fmt.Errorf("error parse apply validTxs: %s", err.Error())
 	}
 
-	itxs, err := fetchIntFromPair(parts[3])
+	itxs, err := f.int("invalidTxs", "num_invalid_txs")
 	if err != nil {
 		return nil, fmt.Errorf("error parse apply invalidTxs: %s", err.Error())
 	}
 
-	return NewTmInfoApply(h, vtxs, itxs, stamp), nil
+	return withExtra(NewTmInfoApply(h, vtxs, itxs, stamp), f), nil
 }
 
 func (i *TMInfoApply) Data() string {
@@ -194,6 +405,15 @@ func (i *TMInfoApply) Format() []string {
 	return []string{strconv.Itoa(i.height), i.stamp.Format(time.RFC3339)}
 }
 
+func (i *TMInfoApply) Fields() []Field {
+	return append([]Field{
+		IntField("height", i.height),
+		TimeField("stamp", i.stamp),
+		IntField("valid_txs", i.validTxNum),
+		IntField("invalid_txs", i.invalidTxNum),
+	}, i.extraFields()...)
+}
+
 func (i *TMInfoApply) Stamp() time.Time {
 	return i.stamp
 }
@@ -212,50 +432,78 @@ const itemNameCommit = "Committed state"
 var _ Item = (*TMInfoCommit)(nil)
 
 type TMInfoCommit struct {
+	itemSource
+	tmExtra
+
 	height  int
 	txNum   int
 	appHash string
 	stamp   time.Time
 	cost    time.Duration
+	// costKnown is false for the first commit of a run, which has no previous
+	// commit to count the cost from, its cost is exported empty
+	costKnown bool
 }
 
 func NewTMInfoCommit(h, tn int, hash string, s time.Time, c time.Duration) Item {
 	return &TMInfoCommit{
-		height:  h,
-		txNum:   tn,
-		appHash: hash,
-		stamp:   s,
-		cost:    c,
+		height:    h,
+		txNum:     tn,
+		appHash:   hash,
+		stamp:     s,
+		cost:      c,
+		costKnown: true,
 	}
 }
 
-func parseTailCommit(stamp time.Time, tail string) (Item, error) {
-	parts := strings.Split(tail, " ")
-	if len(parts) != 4 {
-		return nil, fmt.Errorf("malformed commit tail: %s", tail)
+// CostKnown tells whether the block cost was counted from a previous commit
+func (i *TMInfoCommit) CostKnown() bool {
+	return i.costKnown
+}
+
+// setCostFrom counts the cost from the previous commit, unknown without one
+func (i *TMInfoCommit) setCostFrom(prev time.Time, committed bool) {
+	i.costKnown = committed
+	i.cost = 0
+	if committed {
+		i.cost = i.stamp.Sub(prev)
 	}
+}
 
-	h, err := fetchIntFromPair(parts[1])
+func (s *TMState) parseTailCommit(stamp time.Time, f tmFields) (Item, error) {
+	h, err := f.int("height")
 	if err != nil {
 		return nil, fmt.Errorf("error parse commit height: %s", err.Error())
 	}
 
-	txs, err := fetchIntFromPair(parts[2])
+	txs, err := f.int("txs", "num_txs")
 	if err != nil {
 		return nil, fmt.Errorf("error parse commit txs: %s", err.Error())
 	}
 
-	hashParts := strings.Split(parts[3], "=")
-	if len(hashParts) != 2 {
-		return nil, fmt.Errorf("malformed commit appHash: %s", parts[3])
+	hash, err := f.lookup("appHash", "app_hash")
+	if err != nil {
+		return nil, fmt.Errorf("malformed commit appHash: %s", err.Error())
 	}
 
-	c := stamp.Sub(currentHeightStamp)
+	return withExtra(s.commit(stamp, h, txs, hash), f), nil
+}
+
+// commit moves the state to the committed height, the block cost counts from
+// the previous commit
+func (s *TMState) commit(stamp time.Time, h, txs int, hash string) Item {
+	ret := &TMInfoCommit{
+		height:  h,
+		txNum:   txs,
+		appHash: hash,
+		stamp:   stamp,
+	}
+	ret.setCostFrom(s.heightStamp, s.committed)
 	// update current height info
-	SetCurrentHeight(h)
-	SetCurrentHeightStamp(stamp)
+	s.SetHeight(h)
+	s.SetHeightStamp(stamp)
 
-	return NewTMInfoCommit(h, txs, hashParts[1], stamp, c), nil
+	return ret
 }
 
 func (i *TMInfoCommit) Data() string {
@@ -267,10 +515,27 @@ func (i TMInfoCommit) Header() []string {
 }
 
 func (i *TMInfoCommit) Format() []string {
-	asMS := strconv.FormatInt(i.cost.Milliseconds(), 10)
+	asMS := ""
+	if i.costKnown {
+		asMS = strconv.FormatInt(i.cost.Milliseconds(), 10)
+	}
 	return []string{strconv.Itoa(i.height), i.stamp.Format(time.RFC3339), strconv.Itoa(i.txNum), i.appHash, asMS}
 }
 
+func (i *TMInfoCommit) Fields() []Field {
+	cost := NullField("block_cost", FieldDuration)
+	if i.costKnown {
+		cost = DurationField("block_cost", i.cost)
+	}
+	return append([]Field{
+		IntField("height", i.height),
+		TimeField("stamp", i.stamp),
+		IntField("txs", i.txNum),
+		StringField("hash", i.appHash),
+		cost,
+	}, i.extraFields()...)
+}
+
 func (i *TMInfoCommit) Stamp() time.Time {
 	return i.stamp
 }
@@ -289,6 +554,9 @@ const itemNameEndBlocker = "EndBlocker Time"
 var _ Item = (*TMInfoEndBlocker)(nil)
 
 type TMInfoEndBlocker struct {
+	itemSource
+	tmExtra
+
 	stamp  time.Time
 	height int
 	module string
@@ -304,40 +572,23 @@ func NewTMInfoEndBlocker(s time.Time, h int, m string, c time.Duration) Item {
 	}
 }
 
-func fetchDurationFromPair(pair string) (time.Duration, error) {
-	parts := strings.Split(pair, "=")
-	if len(parts) != 2 {
-		return 0, fmt.Errorf("malformed pair: %s", pair)
-	}
-	dur, err := time.ParseDuration(parts[1])
-	if err != nil {
-		return 0, fmt.Errorf("error parse duration: %s", err.Error())
-	}
-	return dur, nil
-}
-
-func parseTailEndBlocker(stamp time.Time, tail string) (Item, error) {
-	parts := strings.Split(tail, " ")
-	if len(parts) != 4 {
-		return nil, fmt.Errorf("malformed endblocker tail: %s", tail)
-	}
-
-	h, err := fetchIntFromPair(parts[1])
+func parseTailEndBlocker(stamp time.Time, f tmFields) (Item, error) {
+	h, err := f.int("height")
 	if err != nil {
 		return nil, fmt.Errorf("error parse endblocker height: %s", err.Error())
 	}
 
-	nameParts := strings.Split(parts[2], "=")
-	if len(nameParts) != 2 {
-		return nil, fmt.Errorf("malformed endblocker name: %s", parts[2])
+	m, err := f.lookup("name")
+	if err != nil {
+		return nil, fmt.Errorf("malformed endblocker name: %s", err.Error())
 	}
 
-	c, err := fetchDurationFromPair(parts[3])
+	c, err := f.duration("cost")
 	if err != nil {
 		return nil, fmt.Errorf("error parse endblocker cost: %s", err.Error())
 	}
 
-	return NewTMInfoEndBlocker(stamp, h, nameParts[1], c), nil
+	return withExtra(NewTMInfoEndBlocker(stamp, h, m, c), f), nil
 }
 
 func (i *TMInfoEndBlocker) Data() string {
@@ -354,6 +605,15 @@ func (i *TMInfoEndBlocker) Format() []string {
 	return []string{strconv.Itoa(i.height), i.module, asMS}
 }
 
+func (i *TMInfoEndBlocker) Fields() []Field {
+	return append([]Field{
+		TimeField("stamp", i.stamp),
+		IntField("height", i.height),
+		StringField("module", i.module),
+		DurationField("endblocker_cost", i.cost),
+	}, i.extraFields()...)
+}
+
 func (i *TMInfoEndBlocker) Stamp() time.Time {
 	return i.stamp
 }
@@ -372,6 +632,9 @@ const itemNameHandler = "Deliver Time"
 var _ Item = (*TMInfoHandler)(nil)
 
 type TMInfoHandler struct {
+	itemSource
+	tmExtra
+
 	stamp  time.Time
 	height int
 	txType string
@@ -387,28 +650,23 @@ func NewTMInfoHandler(s time.Time, h int, t string, c time.Duration) Item {
 	}
 }
 
-func parseTailHandler(stamp time.Time, tail string) (Item, error) {
-	parts := strings.Split(tail, " ")
-	if len(parts) != 4 {
-		return nil, fmt.Errorf("malformed handler tail: %s", tail)
-	}
-
-	h, err := fetchIntFromPair(parts[1])
+func parseTailHandler(stamp time.Time, f tmFields) (Item, error) {
+	h, err := f.int("height")
 	if err != nil {
 		return nil, fmt.Errorf("error parse handler height: %s", err.Error())
 	}
 
-	typeParts := strings.Split(parts[2], "=")
-	if len(typeParts) != 2 {
-		return nil, fmt.Errorf("malformed handler type: %s", parts[2])
+	t, err := f.lookup("type", "name")
+	if err != nil {
+		return nil, fmt.Errorf("malformed handler type: %s", err.Error())
 	}
 
-	c, err := fetchDurationFromPair(parts[3])
+	c, err := f.duration("cost")
 	if err != nil {
 		return nil, fmt.Errorf("error parse handler cost: %s", err.Error())
 	}
 
-	return NewTMInfoHandler(stamp, h, typeParts[1], c), nil
+	return withExtra(NewTMInfoHandler(stamp, h, t, c), f), nil
 }
 
 func (i *TMInfoHandler) Data() string {
@@ -425,6 +683,15 @@ func (i *TMInfoHandler) Format() []string {
 	return []string{strconv.Itoa(i.height), i.txType, asMS}
 }
 
+func (i *TMInfoHandler) Fields() []Field {
+	return append([]Field{
+		TimeField("stamp", i.stamp),
+		IntField("height", i.height),
+		StringField("type", i.txType),
+		DurationField("handler_cost", i.cost),
+	}, i.extraFields()...)
+}
+
 func (i *TMInfoHandler) Stamp() time.Time {
 	return i.stamp
 }
@@ -443,6 +710,9 @@ const itemNameQuerier = "Query Time"
 var _ Item = (*TMInfoQuerier)(nil)
 
 type TMInfoQuerier struct {
+	itemSource
+	tmExtra
+
 	stamp  time.Time
 	height int
 	path   string
@@ -458,24 +728,19 @@ func NewTMInfoQuerier(s time.Time, h int, p string, c time.Duration) Item {
 	}
 }
 
-func parseTailQuerier(stamp time.Time, tail string) (Item, error) {
-	parts := strings.Split(tail, " ")
-	if len(parts) != 3 {
-		return nil, fmt.Errorf("malformed querier tail: %s", tail)
-	}
-
-	pathParts := strings.Split(parts[1], "=")
-	if len(pathParts) != 2 {
-		return nil, fmt.Errorf("malformed querier path: %s", parts[1])
+func (s *TMState) parseTailQuerier(stamp time.Time, f tmFields) (Item, error) {
+	p, err := f.lookup("path")
+	if err != nil {
+		return nil, fmt.Errorf("malformed querier path: %s", err.Error())
 	}
-	path := strings.TrimRight(strings.TrimLeft(pathParts[1], "["), "]")
+	path := strings.TrimRight(strings.TrimLeft(p, "["), "]")
 
-	c, err := fetchDurationFromPair(parts[2])
+	c, err := f.duration("cost")
 	if err != nil {
 		return nil, fmt.Errorf("error parse querier time: %s", err.Error())
 	}
 
-	return NewTMInfoQuerier(stamp, currentHeight, path, c), nil
+	return withExtra(NewTMInfoQuerier(stamp, s.height, path, c), f), nil
 }
 
 func (i *TMInfoQuerier) Data() string {
@@ -492,6 +757,15 @@ func (i *TMInfoQuerier) Format() []string {
 	return []string{strconv.Itoa(i.height), i.path, asMS}
 }
 
+func (i *TMInfoQuerier) Fields() []Field {
+	return append([]Field{
+		TimeField("stamp", i.stamp),
+		IntField("height", i.height),
+		StringField("path", i.path),
+		DurationField("querier_cost", i.cost),
+	}, i.extraFields()...)
+}
+
 func (i *TMInfoQuerier) Stamp() time.Time {
 	return i.stamp
 }
@@ -508,10 +782,15 @@ func (i TMInfoQuerier) Level() ItemLevel {
 var _ Item = (*TMInfoIgnore)(nil)
 
 type TMInfoIgnore struct {
+	itemSource
+
 	stamp  time.Time
 	height int
 	head   string
 	tail   string
+	// level as logged, the formats with levels besides info and error tell
+	// the debug and warn lines apart
+	level ItemLevel
 }
 
 func NewTMInfoIgnore(s time.Time, h int, head, tail string) Item {
@@ -520,6 +799,7 @@ func NewTMInfoIgnore(s time.Time, h int, head, tail string) Item {
 		height: h,
 		head:   head,
 		tail:   tail,
+		level:  LevelInfo,
 	}
 }
 
@@ -535,6 +815,15 @@ func (i *TMInfoIgnore) Format() []string {
 	return []string{strconv.Itoa(i.height), i.head, i.tail}
 }
 
+func (i *TMInfoIgnore) Fields() []Field {
+	return []Field{
+		TimeField("stamp", i.stamp),
+		IntField("height", i.height),
+		StringField("head", i.head),
+		StringField("tail", i.tail),
+	}
+}
+
 func (i *TMInfoIgnore) Stamp() time.Time {
 	return i.stamp
 }
@@ -544,51 +833,80 @@ func (i TMInfoIgnore) Class() string {
 }
 
 func (i TMInfoIgnore) Level() ItemLevel {
-	return LevelInfo
+	return i.level
 }
 
 // ------------------------- //
 
-type parseTailFunc = func(time.Time, string) (Item, error)
+type parseTailFunc = func(time.Time, tmFields) (Item, error)
 
-func ParseTMInfo(lineNum int, lineText string) (Item, error) {
-	parts := strings.Split(lineText, TMItemSep)
+// tailParser returns the parser of the tail of the item named, nil for the
+// items ignored
+func (s *TMState) tailParser(name string) parseTailFunc {
+	switch name {
+	case itemNameApply:
+		return parseTailApply
+	case itemNameCommit:
+		return s.parseTailCommit
+	case itemNameEndBlocker:
+		return parseTailEndBlocker
+	case itemNameHandler:
+		return parseTailHandler
+	case itemNameQuerier:
+		return s.parseTailQuerier
+	default:
+	}
+	if isConsensusItem(name) {
+		return func(stamp time.Time, f tmFields) (Item, error) {
+			return parseConsensus(stamp, name, f)
+		}
+	}
+	return nil
+}
+
+// buildInfo makes the item of the name out of its fields
+func (s *TMState) buildInfo(stamp time.Time, name string, f tmFields) (Item, error) {
+	if tailParser := s.tailParser(name); tailParser != nil {
+		return tailParser(stamp, f)
+	}
+	return NewTMInfoIgnore(stamp, s.height, name, f.tail()), nil
+}
+
+func (s *TMState) ParseInfo(lineNum int, lineText string) (Item, error) {
+	parts := strings.SplitN(lineText, TMItemSep, 2)
 	if len(parts) != 2 {
 		return nil, fmt.Errorf("%d: malformed tm item: %s", lineNum, lineText)
 	}
 
 	// parse head
-	headParts := strings.Split(parts[0], "]")
+	headParts := strings.SplitN(parts[0], "]", 2)
+	if len(headParts) != 2 {
+		return nil, fmt.Errorf("%d: malformed tm head: %s", lineNum, parts[0])
+	}
 
 	stamp, err := parseTMStamp(headParts[0])
 	if err != nil {
 		return nil, fmt.Errorf("%d: error parse stamp: %s", lineNum, err.Error())
 	}
 
-	var tailParser parseTailFunc
 	name := strings.TrimSpace(headParts[1])
-	switch name {
-	case itemNameApply:
-		tailParser = parseTailApply
-	case itemNameCommit:
-		tailParser = parseTailCommit
-	case itemNameEndBlocker:
-		tailParser = parseTailEndBlocker
-	case itemNameHandler:
-		tailParser = parseTailHandler
-	case itemNameQuerier:
-		tailParser = parseTailQuerier
-	default:
+	tailParser := s.tailParser(name)
+	if tailParser == nil {
+		return NewTMInfoIgnore(stamp, s.height, name, parts[1]), nil
 	}
 
-	var ret Item
-	if tailParser != nil {
-		if ret, err = tailParser(stamp, parts[1]); err != nil {
-			return nil, fmt.Errorf("%d: error parse tail: %s", lineNum, err.Error())
-		}
-	} else {
-		ret = NewTMInfoIgnore(stamp, currentHeight, name, parts[1])
+	// the tail starts with the module value
+	fields, err := parseTailFields(TMItemSep + parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%d: error split tail: %s", lineNum, err.Error())
+	}
+	ret, err := tailParser(stamp, fields)
+	if err != nil {
+		return nil, fmt.Errorf("%d: error parse tail: %s", lineNum, err.Error())
 	}
-
 	return ret, nil
 }
+
+func ParseTMInfo(lineNum int, lineText string) (Item, error) {
+	return defaultParser.tm.ParseInfo(lineNum, lineText)
+}